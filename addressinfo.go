@@ -0,0 +1,125 @@
+package gobtcsign
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+// DefaultInspectNets 默认检查的网络，InspectAddress 没有指定网络时使用
+// 注意比特币的 testnet3、signet、regtest 和狗狗币的部分网络使用相同的地址前缀，这些地址是无法自动区分的，需要调用者指定网络
+var DefaultInspectNets = []*chaincfg.Params{
+	&chaincfg.MainNetParams,
+	&chaincfg.TestNet3Params,
+	&chaincfg.SigNetParams,
+	&chaincfg.RegressionNetParams,
+	&dogecoin.MainNetParams,
+	&dogecoin.TestNetParams,
+	&dogecoin.RegressionNetParams,
+}
+
+// AddressInfo 地址的检查结果
+type AddressInfo struct {
+	Address        btcutil.Address      //解码后的地址
+	NetParams      *chaincfg.Params     //地址所属的网络
+	ScriptClass    txscript.ScriptClass //地址对应的脚本类型
+	WitnessVersion int                  //隔离见证的版本，0是 P2WPKH/P2WSH，1是 P2TR，不是隔离见证地址时是-1
+	CanSign        bool                 //这个库能不能签名花费这个地址的UTXO，详见 Sign 支持的地址类型
+}
+
+// GetNetName 网络的名称，狗狗币的网络名称和比特币的相同，因此加上前缀以便区分
+func (info *AddressInfo) GetNetName() string {
+	return GetNetName(info.NetParams)
+}
+
+// AmbiguousAddressError 地址同时属于多个网络时返回这个错误，可以使用 errors.Is 判断 ErrAmbiguousAddress
+type AmbiguousAddressError struct {
+	Address string
+	Nets    []*chaincfg.Params //地址能匹配的全部网络
+}
+
+func (e *AmbiguousAddressError) Error() string {
+	var names = make([]string, 0, len(e.Nets))
+	for _, netParams := range e.Nets {
+		names = append(names, GetNetName(netParams))
+	}
+	return fmt.Sprintf("ambiguous-address address=%s nets=[%s]", e.Address, strings.Join(names, ", "))
+}
+
+func (e *AmbiguousAddressError) Is(target error) bool {
+	return target == ErrAmbiguousAddress
+}
+
+// InspectAddress 检查地址属于哪个网络，以及地址的脚本类型、隔离见证版本和能否签名
+// candidates 是候选的网络，为空时使用 DefaultInspectNets，地址匹配多个网络时返回 *AmbiguousAddressError，这时需要缩小候选的范围
+// 十六进制的公钥不是地址，即使 btcutil.DecodeAddress 能解码它，这里也会拒绝
+func InspectAddress(address string, candidates ...*chaincfg.Params) (*AddressInfo, error) {
+	if len(candidates) == 0 {
+		candidates = DefaultInspectNets
+	}
+	var matches []*AddressInfo
+	for _, netParams := range candidates {
+		info, ok := inspectAddressOnNet(address, netParams)
+		if ok {
+			matches = append(matches, info)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, errors.Errorf("wrong address=%s not match any net", address)
+	case 1:
+		return matches[0], nil
+	default:
+		var nets = make([]*chaincfg.Params, 0, len(matches))
+		for _, info := range matches {
+			nets = append(nets, info.NetParams)
+		}
+		return nil, errors.WithStack(&AmbiguousAddressError{Address: address, Nets: nets})
+	}
+}
+
+// inspectAddressOnNet 检查地址是不是这个网络的，bech32 地址在任何网络下都能解码，因此还需要 IsForNet 检查
+func inspectAddressOnNet(address string, netParams *chaincfg.Params) (*AddressInfo, bool) {
+	walletAddress, err := btcutil.DecodeAddress(address, netParams)
+	if err != nil || !walletAddress.IsForNet(netParams) {
+		return nil, false
+	}
+	info := &AddressInfo{
+		Address:        walletAddress,
+		NetParams:      netParams,
+		WitnessVersion: -1,
+	}
+	switch walletAddress.(type) {
+	case *btcutil.AddressPubKeyHash:
+		info.ScriptClass = txscript.PubKeyHashTy
+		info.CanSign = true
+	case *btcutil.AddressScriptHash:
+		info.ScriptClass = txscript.ScriptHashTy
+	case *btcutil.AddressWitnessPubKeyHash:
+		info.ScriptClass = txscript.WitnessV0PubKeyHashTy
+		info.WitnessVersion = 0
+		info.CanSign = !dogecoin.IsDogeNet(netParams) //狗狗币不支持隔离见证
+	case *btcutil.AddressWitnessScriptHash:
+		info.ScriptClass = txscript.WitnessV0ScriptHashTy
+		info.WitnessVersion = 0
+	case *btcutil.AddressTaproot:
+		info.ScriptClass = txscript.WitnessV1TaprootTy
+		info.WitnessVersion = 1
+	default: //十六进制的公钥
+		return nil, false
+	}
+	return info, true
+}
+
+// GetNetName 网络的名称，狗狗币的网络名称加上 "dogecoin-" 前缀，以免和比特币的网络混淆
+func GetNetName(netParams *chaincfg.Params) string {
+	if dogecoin.IsDogeNet(netParams) {
+		return "dogecoin-" + netParams.Name
+	}
+	return netParams.Name
+}
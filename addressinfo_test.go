@@ -0,0 +1,66 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func TestInspectAddress(t *testing.T) {
+	info, err := InspectAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	require.NoError(t, err)
+	require.Equal(t, chaincfg.MainNetParams.Name, info.NetParams.Name)
+	require.Equal(t, txscript.PubKeyHashTy, info.ScriptClass)
+	require.Equal(t, -1, info.WitnessVersion)
+	require.True(t, info.CanSign)
+
+	info, err = InspectAddress("bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297")
+	require.NoError(t, err)
+	require.Equal(t, "mainnet", info.GetNetName())
+	require.Equal(t, txscript.WitnessV1TaprootTy, info.ScriptClass)
+	require.Equal(t, 1, info.WitnessVersion)
+	require.False(t, info.CanSign)
+
+	//狗狗币测试网的地址前缀是独有的
+	info, err = InspectAddress("nkgVWbNrUowCG4mkWSzA7HHUDe3XyL2NaC")
+	require.NoError(t, err)
+	require.Equal(t, "dogecoin-testnet", info.GetNetName())
+	require.True(t, info.CanSign)
+}
+
+func TestInspectAddress_Ambiguous(t *testing.T) {
+	const address = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	//testnet3 和 signet 的地址前缀相同
+	_, err := InspectAddress(address)
+	require.ErrorIs(t, err, ErrAmbiguousAddress)
+	var ambiguousErr *AmbiguousAddressError
+	require.True(t, errors.As(err, &ambiguousErr))
+	require.Len(t, ambiguousErr.Nets, 2)
+	t.Log(err)
+
+	//指定网络以后就不再有歧义
+	info, err := InspectAddress(address, &chaincfg.TestNet3Params, &dogecoin.TestNetParams)
+	require.NoError(t, err)
+	require.Equal(t, "testnet3", info.GetNetName())
+	require.Equal(t, txscript.WitnessV0PubKeyHashTy, info.ScriptClass)
+	require.Equal(t, 0, info.WitnessVersion)
+	require.True(t, info.CanSign)
+}
+
+func TestInspectAddress_Wrong(t *testing.T) {
+	_, err := InspectAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb") //校验和不对
+	require.Error(t, err)
+
+	//十六进制的公钥不是地址
+	_, err = InspectAddress("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	require.Error(t, err)
+
+	//只在给定的网络里找
+	_, err = InspectAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", &chaincfg.TestNet3Params)
+	require.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package gobtcsign
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+// PayToAnchorScript 是 P2A（pay-to-anchor）输出的脚本，即 OP_1 <0x4e73>，是个隔离见证版本1的短程序
+// 任何人都可以不带签名地花费它，专门用来给交易做CPFP加速，详见 Bitcoin Core 28.0 的发布说明
+var PayToAnchorScript = []byte{txscript.OP_1, txscript.OP_DATA_2, 0x4e, 0x73}
+
+// payToAnchorInputSize 花费P2A输出的输入大小，包括 outpoint 36 字节、空的解锁脚本 1 字节、序号 4 字节，见证是空的
+const payToAnchorInputSize = 32 + 4 + 1 + 4
+
+// IsPayToAnchor 是不是P2A输出的脚本
+func IsPayToAnchor(pkScript []byte) bool {
+	return bytes.Equal(pkScript, PayToAnchorScript)
+}
+
+// GetPayToAnchorAddress 得到P2A输出的地址，比如主网是 bc1pfeessrawgf
+// btcutil 不支持2字节的见证程序，因此这里单独编码，GetAddressPkScript 也能识别这个地址
+func GetPayToAnchorAddress(netParams *chaincfg.Params) (string, error) {
+	if dogecoin.IsDogeNet(netParams) || netParams.Bech32HRPSegwit == "" {
+		return "", errors.Errorf("wrong net=%s not support p2a address", netParams.Name)
+	}
+	return encodeSegWitAddress(netParams.Bech32HRPSegwit, 1, PayToAnchorScript[2:])
+}
+
+// NewPayToAnchorOutput 创建P2A输出，数量为0时是临时灰尘（ephemeral dust），只允许出现在 TRUC（版本3）交易里
+// 这时交易自身的手续费也必须是0，由花费这个P2A输出的子交易来支付父子交易的全部手续费
+func NewPayToAnchorOutput(amount int64) OutType {
+	return OutType{
+		Target: AddressTuple{PkScript: PayToAnchorScript},
+		Amount: amount,
+	}
+}
+
+// checkAnchorOutputs 检查P2A输出，狗狗币没有隔离见证因此不支持，灰尘数量的P2A输出只能有一个，而且需要是手续费为0的 TRUC 交易
+func (param *BitcoinTxParams) checkAnchorOutputs(netParams *chaincfg.Params) error {
+	var dustCount = 0
+	for idx, output := range param.OutList {
+		//输出可能只填写了地址，因此按脚本判断
+		pkScript, err := output.GetPkScript(netParams)
+		if err != nil {
+			return errors.WithMessagef(err, "wrong out[%d] target.address->pk-script", idx)
+		}
+		if !IsPayToAnchor(pkScript) {
+			continue
+		}
+		if dogecoin.IsDogeNet(netParams) {
+			return errors.Errorf("wrong net=%s not support p2a output", netParams.Name)
+		}
+		if !txrules.IsDustOutput(wire.NewTxOut(output.Amount, PayToAnchorScript), txrules.DefaultRelayFeePerKb) {
+			continue
+		}
+		if dustCount++; dustCount > 1 {
+			return errors.WithMessage(ErrDustOutput, "wrong dust p2a output count > 1")
+		}
+		if !param.IsTruc() {
+			return errors.WithMessagef(ErrDustOutput, "wrong dust p2a output amount=%d, need truc tx version=%d", output.Amount, TrucTxVersion)
+		}
+		if fee := param.GetFee(); fee != 0 {
+			return errors.WithMessagef(ErrDustOutput, "wrong dust p2a output with tx fee=%d, need zero fee", fee)
+		}
+	}
+	return nil
+}
+
+// NewAnchorVin 得到花费父交易P2A输出的输入，这个输入不需要签名，签名和验签时都会跳过它
+func (p *CpfpParent) NewAnchorVin(rbfInfo RBFConfig) (VinType, error) {
+	if p.MsgTx == nil {
+		return VinType{}, errors.New("wrong cpfp-parent msg-tx is none")
+	}
+	for idx, out := range p.MsgTx.TxOut {
+		if IsPayToAnchor(out.PkScript) {
+			return p.NewVin(uint32(idx), AddressTuple{PkScript: PayToAnchorScript}, rbfInfo)
+		}
+	}
+	return VinType{}, errors.Errorf("wrong cpfp-parent tx=%s has no p2a output", p.TxHash)
+}
+
+// checkAnchorInputSign P2A输入不需要签名，只要解锁脚本和见证都是空的就行
+func checkAnchorInputSign(txIn *wire.TxIn, idx int) error {
+	if len(txIn.SignatureScript) != 0 {
+		return errors.WithStack(newVerifyError(idx, errors.WithMessage(ErrAnchorNotKeyless, "p2a input has sig-script"), "wrong p2a input must be keyless"))
+	}
+	if len(txIn.Witness) != 0 {
+		return errors.WithStack(newVerifyError(idx, errors.WithMessage(ErrAnchorNotKeyless, "p2a input has witness"), "wrong p2a input must be keyless"))
+	}
+	return nil
+}
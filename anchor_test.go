@@ -0,0 +1,185 @@
+package gobtcsign
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func caseNewAnchorParentParam(anchorAmount int64, version int32) *BitcoinTxParams {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	return &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 13089 - anchorAmount,
+			},
+			NewPayToAnchorOutput(anchorAmount),
+		},
+		RBFInfo: *NewRBFActive(),
+		Version: version,
+	}
+}
+
+func TestPayToAnchorScript(t *testing.T) {
+	require.Equal(t, "51024e73", hex.EncodeToString(PayToAnchorScript))
+	require.True(t, txscript.IsWitnessProgram(PayToAnchorScript))
+	require.True(t, IsPayToAnchor(PayToAnchorScript))
+
+	size, err := CalculateChangePkScriptSize(PayToAnchorScript)
+	require.NoError(t, err)
+	require.Equal(t, 4, size)
+
+	vSize, err := estimateInputVSize(PayToAnchorScript)
+	require.NoError(t, err)
+	require.Equal(t, 41, vSize)
+}
+
+func TestBitcoinTxParams_AnchorOutput(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	//零手续费的 TRUC 交易可以带一个零数量的P2A输出
+	signParam, err := caseNewAnchorParentParam(0, TrucTxVersion).CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.True(t, IsPayToAnchor(signParam.MsgTx.TxOut[1].PkScript))
+
+	//不是 TRUC 交易时不允许
+	_, err = caseNewAnchorParentParam(0, 2).CreateTxSignParams(&netParams)
+	require.Error(t, err)
+
+	//有手续费时不允许
+	param := caseNewAnchorParentParam(0, TrucTxVersion)
+	param.OutList[0].Amount -= 200
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err)
+
+	//不能有两个灰尘P2A输出
+	param = caseNewAnchorParentParam(0, TrucTxVersion)
+	param.OutList = append(param.OutList, NewPayToAnchorOutput(0))
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err)
+
+	//只填写P2A地址的输出也会被识别出来
+	p2aAddress, err := GetPayToAnchorAddress(&netParams)
+	require.NoError(t, err)
+	param = caseNewAnchorParentParam(0, 2)
+	param.OutList[len(param.OutList)-1].Target = *NewAddressTuple(p2aAddress)
+	_, err = param.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrDustOutput)
+
+	//不是灰尘的P2A输出在普通交易里也能用
+	param = caseNewAnchorParentParam(240, 0)
+	param.OutList[0].Amount -= 200
+	_, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+
+	//狗狗币不支持
+	dogeParam := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("173d5e1b33bb8a3d5c2c4ad3b2ff0d8e9cde10b8fb5d1a1dbdb3c3d0d8f8e0a1", 0),
+				Sender:   *NewAddressTuple("nkgVWbNrUowCG4mkWSzA7HHUDe3XyL2NaC"),
+				Amount:   100000000,
+			},
+		},
+		OutList: []OutType{NewPayToAnchorOutput(100000000)},
+	}
+	_, err = dogeParam.CreateTxSignParams(&dogecoin.TestNetParams)
+	require.Error(t, err)
+}
+
+func TestBuildCpfpTx_Anchor(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	parentParam := caseNewAnchorParentParam(0, TrucTxVersion)
+	parentSignParam, err := parentParam.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, parentSignParam))
+
+	utxo := NewSenderAmountUtxo(&parentParam.VinList[0].Sender, parentParam.VinList[0].Amount)
+	preImp := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{parentParam.VinList[0].OutPoint: utxo})
+	parent, err := NewCpfpParentFromMsgTx(parentSignParam.MsgTx, preImp)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(0), parent.Fee)
+
+	anchorVin, err := parent.NewAnchorVin(*NewRBFActive())
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), anchorVin.OutPoint.Index)
+	require.True(t, anchorVin.Unconfirmed)
+
+	res, err := BuildCpfpTx(&CpfpParam{
+		Parents: []*CpfpParent{parent},
+		VinList: []VinType{
+			anchorVin,
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4900,
+			},
+		},
+		Target:       *NewAddressTuple(senderAddress),
+		FeeRatePerKb: 2000,
+		RBFInfo:      *NewRBFActive(),
+		Version:      TrucTxVersion,
+	}, &netParams)
+	require.NoError(t, err)
+	require.True(t, res.TxParams.IsTruc())
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.Empty(t, signParam.MsgTx.TxIn[0].Witness) //P2A输入不需要签名
+	require.NotEmpty(t, signParam.MsgTx.TxIn[1].Witness)
+
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, res.TxParams.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	//预估的大小不小于实际的大小
+	childVSize := res.PackageVSize - parent.VSize
+	require.GreaterOrEqual(t, childVSize, GetMsgTxVSize(signParam.MsgTx))
+	require.LessOrEqual(t, childVSize-GetMsgTxVSize(signParam.MsgTx), 3)
+	require.GreaterOrEqual(t, int64(res.PackageFee), feeForVSizeCeil(2000, res.PackageVSize))
+
+	//P2A输入带了签名数据时验签失败
+	signParam.MsgTx.TxIn[0].Witness = wire.TxWitness{{1}}
+	err = res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams)
+	require.ErrorIs(t, err, ErrVerifySign)
+	require.ErrorIs(t, err, ErrAnchorNotKeyless)
+}
+
+func TestGetPayToAnchorAddress(t *testing.T) {
+	address, err := GetPayToAnchorAddress(&chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, "bc1pfeessrawgf", address)
+
+	pkScript, err := GetAddressPkScript(address, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.True(t, IsPayToAnchor(pkScript))
+
+	address, err = GetPayToAnchorAddress(&chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	pkScript, err = NewAddressTuple(address).GetPkScript(&chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, PayToAnchorScript, pkScript)
+
+	//狗狗币没有隔离见证
+	_, err = GetPayToAnchorAddress(&dogecoin.MainNetParams)
+	require.Error(t, err)
+}
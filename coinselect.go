@@ -0,0 +1,476 @@
+package gobtcsign
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// CoinSelectParam 选币参数，从候选的UTXO里挑出足够支付转账金额和手续费的输入
+type CoinSelectParam struct {
+	Candidates    []VinType      //候选的UTXO列表，选币逻辑会从中挑选需要花费的
+	OutList       []OutType      //转账的目标列表，这里不要包含找零输出，找零输出由选币逻辑追加
+	ChangeTo      *ChangeTo      //找零信息，为空或者两个成员皆为空时表示不找零，剩余的都作为手续费
+	FeeRatePerKb  btcutil.Amount //费率，单位是 聪/千字节，和 EstimateTxFee 的参数含义相同
+	DustFee       DustFee        //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit     *DustLimit     //灰尘判定规则，为空时使用比特币的规则，找零是灰尘时就不找零而是并入手续费
+	RelayFeePerKb btcutil.Amount //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	RBFInfo       RBFConfig      //拼出来的交易的RBF配置
+}
+
+// CoinSelector 选币器，根据选币参数返回已经凑够资金（含找零）的交易参数
+type CoinSelector interface {
+	SelectCoins(param *CoinSelectParam, netParams *chaincfg.Params) (*BitcoinTxParams, error)
+}
+
+// GetChangeBuilder 根据选币参数里的找零和灰尘配置得到自动找零的逻辑
+func (param *CoinSelectParam) GetChangeBuilder() *ChangeBuilder {
+	return &ChangeBuilder{
+		ChangeTo:      param.ChangeTo,
+		FeeRatePerKb:  param.FeeRatePerKb,
+		DustFee:       param.DustFee,
+		DustLimit:     param.DustLimit,
+		RelayFeePerKb: param.RelayFeePerKb,
+	}
+}
+
+// coinItem 候选的UTXO及其有效价值，有效价值就是数量减去花费它需要的手续费
+type coinItem struct {
+	vin       VinType
+	effective int64
+}
+
+// coinSelectContext 选币时的上下文，各种选币策略都基于它的结果进行挑选
+type coinSelectContext struct {
+	param        *CoinSelectParam
+	netParams    *chaincfg.Params
+	coins        []*coinItem //有效价值为正数的候选UTXO，有效价值<=0的UTXO花了反而亏钱，因此不参与选币
+	target       int64       //转账总额加上交易公共部分的手续费，选中的有效价值之和需要>=它
+	changeScript []byte      //找零的公钥脚本，为空时表示不找零
+	costOfChange int64       //增加找零输出的代价，包括找零输出本身的手续费和将来花费找零的手续费
+	minChange    int64       //找零的最小数量，低于它时找零就是灰尘
+}
+
+func newCoinSelectContext(param *CoinSelectParam, netParams *chaincfg.Params) (*coinSelectContext, error) {
+	outputs, err := (&BitcoinTxParams{OutList: param.OutList}).GetOutputs(netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong get-outputs")
+	}
+	//没有输入时的交易大小，就是交易的公共部分和输出部分的大小
+	baseSize, err := EstimateSize(nil, outputs, NewNoChange())
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong estimate-size")
+	}
+	var target = feeForVSizeCeil(param.FeeRatePerKb, baseSize) + int64(param.DustFee.SumExtraDustFee(outputs))
+	for _, output := range outputs {
+		target += output.Value
+	}
+
+	var coins = make([]*coinItem, 0, len(param.Candidates))
+	for _, vin := range param.Candidates {
+		pkScript, err := vin.Sender.GetPkScript(netParams)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong sender.address->pk-script")
+		}
+		inputSize, err := estimateInputVSize(pkScript)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-input-size")
+		}
+		//有效价值<=0的UTXO花了反而亏钱，就不选它
+		if effective := vin.Amount - feeForVSizeCeil(param.FeeRatePerKb, inputSize); effective > 0 {
+			coins = append(coins, &coinItem{vin: vin, effective: effective})
+		}
+	}
+
+	builder := param.GetChangeBuilder()
+	changeScript, err := builder.GetChangeTo().GetPkScript()
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong change->pk-script")
+	}
+
+	ctx := &coinSelectContext{
+		param:        param,
+		netParams:    netParams,
+		coins:        coins,
+		target:       target,
+		changeScript: changeScript,
+	}
+	if len(changeScript) > 0 {
+		spendSize, err := estimateInputVSize(changeScript)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-change-input-size")
+		}
+		changeOutSize := wire.NewTxOut(0, changeScript).SerializeSize()
+		ctx.costOfChange = feeForVSizeCeil(param.FeeRatePerKb, changeOutSize) + feeForVSizeCeil(param.FeeRatePerKb, spendSize)
+		ctx.minChange = dustThreshold(builder.GetDustLimit(), changeScript, builder.GetRelayFeePerKb())
+	}
+	return ctx, nil
+}
+
+// checkEnough 检查候选的UTXO是否足够支付
+func (ctx *coinSelectContext) checkEnough() error {
+	if available := sumEffective(ctx.coins); available < ctx.target {
+		return errors.WithStack(&InsufficientFundsError{Available: btcutil.Amount(available), Required: btcutil.Amount(ctx.target)})
+	}
+	return nil
+}
+
+// sortedCoins 按有效价值从大到小排列的候选UTXO
+func (ctx *coinSelectContext) sortedCoins() []*coinItem {
+	coins := append([]*coinItem{}, ctx.coins...)
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].effective > coins[j].effective
+	})
+	return coins
+}
+
+// shuffledCoins 随机排列的候选UTXO
+func (ctx *coinSelectContext) shuffledCoins(rnd *rand.Rand) []*coinItem {
+	coins := append([]*coinItem{}, ctx.coins...)
+	rnd.Shuffle(len(coins), func(i, j int) {
+		coins[i], coins[j] = coins[j], coins[i]
+	})
+	return coins
+}
+
+// newTxParams 根据选中的UTXO拼出交易参数，当找零不是灰尘时追加找零输出，否则把找零并入手续费
+func (ctx *coinSelectContext) newTxParams(coins []*coinItem) (*BitcoinTxParams, error) {
+	return ctx.buildTxParams(coins, ctx.param.GetChangeBuilder())
+}
+
+// newChangelessTxParams 根据选中的UTXO拼出不找零的交易参数，多出来的都并入手续费
+// 分支定界选出的组合多出来的不超过找零代价，这时找零即使不是灰尘也是不划算的，因此不能再交给 ChangeBuilder 判断
+func (ctx *coinSelectContext) newChangelessTxParams(coins []*coinItem) (*BitcoinTxParams, error) {
+	builder := ctx.param.GetChangeBuilder()
+	builder.ChangeTo = NewNoChange()
+	return ctx.buildTxParams(coins, builder)
+}
+
+func (ctx *coinSelectContext) buildTxParams(coins []*coinItem, builder *ChangeBuilder) (*BitcoinTxParams, error) {
+	var vinList = make([]VinType, 0, len(coins))
+	for _, coin := range coins {
+		vinList = append(vinList, coin.vin)
+	}
+	txParams := &BitcoinTxParams{
+		VinList: vinList,
+		OutList: ctx.param.OutList,
+		RBFInfo: ctx.param.RBFInfo,
+	}
+	res, err := builder.Build(txParams, ctx.netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong build-with-change")
+	}
+	return res.TxParams, nil
+}
+
+// BranchAndBoundSelector 分支定界选币，寻找不需要找零的组合，即选中的有效价值恰好落在 [目标, 目标+找零代价] 的区间里
+// 算法参考 Bitcoin Core 的 SelectCoinsBnB 逻辑
+// https://github.com/bitcoin/bitcoin/blob/master/src/wallet/coinselection.cpp
+// 当找不到这样的组合时返回错误，调用方可以再换用其它的选币策略
+type BranchAndBoundSelector struct {
+	MaxTries int //最大搜索次数，避免UTXO很多时搜索太久，为0时使用默认值
+}
+
+func NewBranchAndBoundSelector() *BranchAndBoundSelector {
+	return &BranchAndBoundSelector{MaxTries: 100000} // Bitcoin Core 里也是这个数
+}
+
+func (s *BranchAndBoundSelector) SelectCoins(param *CoinSelectParam, netParams *chaincfg.Params) (*BitcoinTxParams, error) {
+	ctx, err := newCoinSelectContext(param, netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong new-coin-select-context")
+	}
+	if err := ctx.checkEnough(); err != nil {
+		return nil, err
+	}
+	var upper = ctx.target + ctx.costOfChange
+	if len(ctx.changeScript) == 0 {
+		upper = math.MaxInt64 //不找零时多出来的都是手续费，因此只需要找到多出来最少的组合
+	}
+	coins := s.search(ctx.sortedCoins(), ctx.target, upper)
+	if len(coins) == 0 {
+		return nil, errors.New("branch-and-bound no-changeless-solution")
+	}
+	return ctx.newChangelessTxParams(coins)
+}
+
+// search 深度优先搜索，在 [target, upper] 区间里寻找多出来最少的组合，参数需要按有效价值从大到小排列
+func (s *BranchAndBoundSelector) search(coins []*coinItem, target int64, upper int64) []*coinItem {
+	var tries = s.MaxTries
+	if tries <= 0 {
+		tries = NewBranchAndBoundSelector().MaxTries
+	}
+
+	var best []int
+	var bestExcess int64 = math.MaxInt64
+	var picked []int
+
+	var dfs func(idx int, value int64, remaining int64)
+	dfs = func(idx int, value int64, remaining int64) {
+		if tries <= 0 || bestExcess == 0 {
+			return
+		}
+		tries--
+		if value > upper {
+			return //超过上限，再选就更多了
+		}
+		if value >= target {
+			if excess := value - target; excess < bestExcess {
+				bestExcess = excess
+				best = append(best[:0], picked...)
+			}
+			return
+		}
+		if idx >= len(coins) || value+remaining < target {
+			return //把剩下的全选上也不够
+		}
+		coin := coins[idx]
+		// 先尝试选中当前的
+		picked = append(picked, idx)
+		dfs(idx+1, value+coin.effective, remaining-coin.effective)
+		picked = picked[:len(picked)-1]
+		// 再尝试不选当前的，这时和它等值的后续UTXO也都不用再选，因为选它们和选当前的是等价的，前面已经搜索过
+		next, rest := idx+1, remaining-coin.effective
+		for next < len(coins) && coins[next].effective == coin.effective {
+			rest -= coins[next].effective
+			next++
+		}
+		dfs(next, value, rest)
+	}
+	dfs(0, 0, sumEffective(coins))
+
+	var res = make([]*coinItem, 0, len(best))
+	for _, idx := range best {
+		res = append(res, coins[idx])
+	}
+	return res
+}
+
+// KnapsackSelector 背包选币，随机地逼近最优子集，当没有合适的子集时使用最小的足额UTXO
+// 算法参考 Bitcoin Core 的 KnapsackSolver 逻辑
+// https://github.com/bitcoin/bitcoin/blob/master/src/wallet/coinselection.cpp
+type KnapsackSelector struct {
+	Rand       *rand.Rand //随机数源，为空时使用当前时间作为种子，在单元测试里可以设置固定种子以便复现结果，注意它不是并发安全的
+	Iterations int        //随机逼近的次数，为0时使用默认值
+}
+
+func NewKnapsackSelector() *KnapsackSelector {
+	return &KnapsackSelector{Rand: nil, Iterations: 1000} // Bitcoin Core 里也是这个数
+}
+
+func (s *KnapsackSelector) SelectCoins(param *CoinSelectParam, netParams *chaincfg.Params) (*BitcoinTxParams, error) {
+	ctx, err := newCoinSelectContext(param, netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong new-coin-select-context")
+	}
+	if err := ctx.checkEnough(); err != nil {
+		return nil, err
+	}
+	rnd := newSelectRand(s.Rand)
+
+	var target = ctx.target
+	var minChange = ctx.costOfChange + ctx.minChange //找零至少要覆盖找零本身的代价，而且还不能是灰尘
+
+	var applicable []*coinItem
+	var total int64
+	var lowestLarger *coinItem
+	for _, coin := range ctx.shuffledCoins(rnd) {
+		switch {
+		case coin.effective == target:
+			return ctx.newTxParams([]*coinItem{coin}) //恰好相等就是最好的
+		case coin.effective < target+minChange:
+			applicable = append(applicable, coin)
+			total += coin.effective
+		case lowestLarger == nil || coin.effective < lowestLarger.effective:
+			lowestLarger = coin
+		}
+	}
+	if total == target {
+		return ctx.newTxParams(applicable)
+	}
+	if total < target {
+		if lowestLarger == nil {
+			return nil, errors.WithStack(&InsufficientFundsError{Available: btcutil.Amount(total), Required: btcutil.Amount(target)})
+		}
+		return ctx.newTxParams([]*coinItem{lowestLarger})
+	}
+
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return applicable[i].effective > applicable[j].effective
+	})
+	best, bestValue := s.approximateBestSubset(rnd, applicable, total, target)
+	if bestValue != target && total >= target+minChange {
+		best, bestValue = s.approximateBestSubset(rnd, applicable, total, target+minChange)
+	}
+	// 当子集不能恰好凑齐而且找零又太少时，或者最小的足额UTXO更省时，就使用最小的足额UTXO
+	if lowestLarger != nil && ((bestValue != target && bestValue < target+minChange) || lowestLarger.effective <= bestValue) {
+		return ctx.newTxParams([]*coinItem{lowestLarger})
+	}
+	var coins = make([]*coinItem, 0, len(applicable))
+	for idx, coin := range applicable {
+		if best[idx] {
+			coins = append(coins, coin)
+		}
+	}
+	return ctx.newTxParams(coins)
+}
+
+// approximateBestSubset 随机地寻找和>=target而且尽量小的子集
+func (s *KnapsackSelector) approximateBestSubset(rnd *rand.Rand, coins []*coinItem, total int64, target int64) ([]bool, int64) {
+	var iterations = s.Iterations
+	if iterations <= 0 {
+		iterations = NewKnapsackSelector().Iterations
+	}
+
+	var best = make([]bool, len(coins))
+	for idx := range best {
+		best[idx] = true
+	}
+	var bestValue = total
+
+	var included = make([]bool, len(coins))
+	for rep := 0; rep < iterations && bestValue != target; rep++ {
+		for idx := range included {
+			included[idx] = false
+		}
+		var value int64
+		var reached bool
+		for pass := 0; pass < 2 && !reached; pass++ {
+			for idx, coin := range coins {
+				// 第一轮随机地选，第二轮把前面没选的都选上
+				if included[idx] || (pass == 0 && rnd.Intn(2) == 0) {
+					continue
+				}
+				value += coin.effective
+				included[idx] = true
+				if value >= target {
+					reached = true
+					if value < bestValue {
+						bestValue = value
+						copy(best, included)
+					}
+					// 去掉刚选的再试试后面更小的能不能凑得更精确
+					value -= coin.effective
+					included[idx] = false
+				}
+			}
+		}
+	}
+	return best, bestValue
+}
+
+// LargestFirstSelector 从大到小选币，直到足够支付为止，逻辑简单而且输入的个数最少
+type LargestFirstSelector struct{}
+
+func NewLargestFirstSelector() *LargestFirstSelector {
+	return &LargestFirstSelector{}
+}
+
+func (s *LargestFirstSelector) SelectCoins(param *CoinSelectParam, netParams *chaincfg.Params) (*BitcoinTxParams, error) {
+	ctx, err := newCoinSelectContext(param, netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong new-coin-select-context")
+	}
+	if err := ctx.checkEnough(); err != nil {
+		return nil, err
+	}
+	var coins []*coinItem
+	var value int64
+	for _, coin := range ctx.sortedCoins() {
+		coins = append(coins, coin)
+		value += coin.effective
+		if value >= ctx.target {
+			break
+		}
+	}
+	return ctx.newTxParams(coins)
+}
+
+// SingleRandomDrawSelector 随机选币，随机地选直到足够支付而且找零不是灰尘为止，能避免总是花费相同特征的UTXO
+// 算法参考 Bitcoin Core 的 SelectCoinsSRD 逻辑
+// https://github.com/bitcoin/bitcoin/blob/master/src/wallet/coinselection.cpp
+type SingleRandomDrawSelector struct {
+	Rand *rand.Rand //随机数源，为空时使用当前时间作为种子，在单元测试里可以设置固定种子以便复现结果，注意它不是并发安全的
+}
+
+func NewSingleRandomDrawSelector() *SingleRandomDrawSelector {
+	return &SingleRandomDrawSelector{Rand: nil}
+}
+
+func (s *SingleRandomDrawSelector) SelectCoins(param *CoinSelectParam, netParams *chaincfg.Params) (*BitcoinTxParams, error) {
+	ctx, err := newCoinSelectContext(param, netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong new-coin-select-context")
+	}
+	if err := ctx.checkEnough(); err != nil {
+		return nil, err
+	}
+	var target = ctx.target
+	if len(ctx.changeScript) > 0 {
+		target += ctx.costOfChange + ctx.minChange //需要找零时就要让找零不是灰尘
+	}
+	var coins []*coinItem
+	var value int64
+	for _, coin := range ctx.shuffledCoins(newSelectRand(s.Rand)) {
+		coins = append(coins, coin)
+		value += coin.effective
+		if value >= target {
+			break
+		}
+	}
+	//当全部选上也不够找零时，就把全部的都用上，这时多出来的会并入手续费
+	return ctx.newTxParams(coins)
+}
+
+func newSelectRand(rnd *rand.Rand) *rand.Rand {
+	if rnd != nil {
+		return rnd
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+func sumEffective(coins []*coinItem) int64 {
+	var sum int64
+	for _, coin := range coins {
+		sum += coin.effective
+	}
+	return sum
+}
+
+// estimateInputVSize 通过 EstimateSize 计算出花费这种脚本的输入增加的交易大小
+func estimateInputVSize(pkScript []byte) (int, error) {
+	baseSize, err := EstimateSize(nil, nil, NewNoChange())
+	if err != nil {
+		return 0, errors.WithMessage(err, "wrong estimate-size")
+	}
+	size, err := EstimateSize([][]byte{pkScript}, nil, NewNoChange())
+	if err != nil {
+		return 0, errors.WithMessage(err, "wrong estimate-size")
+	}
+	return size - baseSize, nil
+}
+
+// feeForVSizeCeil 按费率计算手续费并且向上取整，选币时分开累加各部分的手续费，向上取整才能保证累加值不小于整体计算的手续费
+func feeForVSizeCeil(feeRatePerKb btcutil.Amount, vSize int) int64 {
+	return (int64(feeRatePerKb)*int64(vSize) + 999) / 1000
+}
+
+// dustThreshold 计算这种脚本的输出不是灰尘的最小数量，灰尘判定对数量是单调的，因此二分查找即可
+func dustThreshold(dustLimit *DustLimit, pkScript []byte, relayFeePerKb btcutil.Amount) int64 {
+	var lo, hi int64 = 0, btcutil.MaxSatoshi
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if dustLimit.IsDustOutput(wire.NewTxOut(mid, pkScript), relayFeePerKb) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
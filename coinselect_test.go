@@ -0,0 +1,173 @@
+package gobtcsign
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+func caseNewCoinSelectParam(t *testing.T, amount int64) *CoinSelectParam {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	changeAddress, err := btcutil.DecodeAddress(senderAddress, &netParams)
+	require.NoError(t, err)
+
+	return &CoinSelectParam{
+		Candidates: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   100000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("fcc889d7f0217694ab46d93f03a200d326c34e317552a6a33cb3fab03aa0b439", 1),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   50000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("5c98431bbb271ea3652168d2b4da8a76573fd8fec104e73f6f6f3a7c6fe6b97d", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   30000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("5fe7486105cb41cc1496fed89296140e00fee5fdc880ac335ea1df9b374f9348", 3),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   20000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("de8ac7275793df0218d7151e420393fa3cf39159147fa6453c5f279f249d6a52", 1),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   50, //这个UTXO的有效价值是负数，不会被选中
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: amount,
+			},
+		},
+		ChangeTo:     &ChangeTo{AddressX: changeAddress},
+		FeeRatePerKb: 1000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}
+}
+
+func caseCheckCoinSelectResult(t *testing.T, param *CoinSelectParam, txParams *BitcoinTxParams) {
+	netParams := chaincfg.TestNet3Params
+
+	for _, vin := range txParams.VinList {
+		require.Greater(t, vin.Amount, int64(50))
+	}
+	fee, err := EstimateTxFee(txParams, &netParams, NewNoChange(), param.FeeRatePerKb, param.DustFee)
+	require.NoError(t, err)
+	t.Log("fee:", txParams.GetFee(), "estimate-fee:", fee, "outputs:", len(txParams.OutList))
+	require.GreaterOrEqual(t, txParams.GetFee(), fee)
+
+	_, err = txParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+}
+
+func TestBranchAndBoundSelector_SelectCoins(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewCoinSelectParam(t, 69800) // 50000+20000 扣除两个输入的手续费以后恰好够用
+	txParams, err := NewBranchAndBoundSelector().SelectCoins(param, &netParams)
+	require.NoError(t, err)
+	caseCheckCoinSelectResult(t, param, txParams)
+
+	require.Len(t, txParams.VinList, 2)
+	require.Len(t, txParams.OutList, 1) //不需要找零
+	require.Equal(t, int64(70000), txParams.VinList[0].Amount+txParams.VinList[1].Amount)
+}
+
+func TestBranchAndBoundSelector_SelectCoins_HighFeeRate(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	//费率是 50 sat/vB 时找零代价约是5千聪，多出来的虽然不是灰尘，但是找零是不划算的，分支定界的结果不能有找零
+	param := caseNewCoinSelectParam(t, 58000)
+	param.FeeRatePerKb = 50000
+	txParams, err := NewBranchAndBoundSelector().SelectCoins(param, &netParams)
+	require.NoError(t, err)
+	caseCheckCoinSelectResult(t, param, txParams)
+
+	require.Len(t, txParams.VinList, 2)
+	require.Len(t, txParams.OutList, 1) //不找零，多出来的并入手续费
+	require.Equal(t, int64(70000), txParams.VinList[0].Amount+txParams.VinList[1].Amount)
+	require.Equal(t, btcutil.Amount(70000-58000), txParams.GetFee())
+
+	//同样的输入交给 ChangeBuilder 时是会找零的
+	res, err := param.GetChangeBuilder().Build(&BitcoinTxParams{VinList: txParams.VinList, OutList: param.OutList, RBFInfo: param.RBFInfo}, &netParams)
+	require.NoError(t, err)
+	require.True(t, res.HasChange())
+}
+
+func TestBranchAndBoundSelector_SelectCoins_NoSolution(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewCoinSelectParam(t, 65000)
+	_, err := NewBranchAndBoundSelector().SelectCoins(param, &netParams)
+	require.Error(t, err)
+	t.Log(err)
+}
+
+func TestKnapsackSelector_SelectCoins(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewCoinSelectParam(t, 65000)
+	selector := &KnapsackSelector{Rand: rand.New(rand.NewSource(0)), Iterations: 1000}
+	txParams, err := selector.SelectCoins(param, &netParams)
+	require.NoError(t, err)
+	caseCheckCoinSelectResult(t, param, txParams)
+}
+
+func TestLargestFirstSelector_SelectCoins(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewCoinSelectParam(t, 120000)
+	txParams, err := NewLargestFirstSelector().SelectCoins(param, &netParams)
+	require.NoError(t, err)
+	caseCheckCoinSelectResult(t, param, txParams)
+
+	require.Len(t, txParams.VinList, 2)
+	require.Equal(t, int64(100000), txParams.VinList[0].Amount)
+	require.Equal(t, int64(50000), txParams.VinList[1].Amount)
+	require.Len(t, txParams.OutList, 2) //有找零
+}
+
+func TestSingleRandomDrawSelector_SelectCoins(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewCoinSelectParam(t, 65000)
+	selector := &SingleRandomDrawSelector{Rand: rand.New(rand.NewSource(0))}
+	txParams, err := selector.SelectCoins(param, &netParams)
+	require.NoError(t, err)
+	caseCheckCoinSelectResult(t, param, txParams)
+}
+
+func TestCoinSelector_InsufficientFunds(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewCoinSelectParam(t, 200000)
+	for _, selector := range []CoinSelector{
+		NewBranchAndBoundSelector(),
+		NewKnapsackSelector(),
+		NewLargestFirstSelector(),
+		NewSingleRandomDrawSelector(),
+	} {
+		_, err := selector.SelectCoins(param, &netParams)
+		require.Error(t, err)
+		t.Log(err)
+	}
+}
@@ -0,0 +1,176 @@
+package gobtcsign
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/pkg/errors"
+)
+
+// CpfpParent 卡在内存池里的父交易，子交易花费它的输出，让矿工把父子交易作为一个整体打包
+type CpfpParent struct {
+	TxHash chainhash.Hash //父交易的哈希
+	MsgTx  *wire.MsgTx    //父交易，允许为空，为空时不能使用 NewVin 得到输入
+	VSize  int            //父交易的 v-size
+	Fee    btcutil.Amount //父交易的手续费
+}
+
+// NewCpfpParent 通过已知的 v-size 和手续费得到父交易，比如从区块浏览器或者节点的 getmempoolentry 拿到的
+func NewCpfpParent(txHash chainhash.Hash, vSize int, fee btcutil.Amount) *CpfpParent {
+	return &CpfpParent{
+		TxHash: txHash,
+		VSize:  vSize,
+		Fee:    fee,
+	}
+}
+
+// NewCpfpParentFromMsgTx 通过父交易计算出 v-size 和手续费，计算手续费需要查询父交易的前置输出
+func NewCpfpParentFromMsgTx(msgTx *wire.MsgTx, preImp GetUtxoFromInterface) (*CpfpParent, error) {
+	param, err := NewCustomParamFromMsgTx(msgTx, preImp)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong new-custom-param-from-msg-tx")
+	}
+	return &CpfpParent{
+		TxHash: msgTx.TxHash(),
+		MsgTx:  msgTx,
+		VSize:  GetMsgTxVSize(msgTx),
+		Fee:    param.GetFee(),
+	}, nil
+}
+
+// NewVin 得到花费父交易输出的输入，这个输入是未确认的
+func (p *CpfpParent) NewVin(index uint32, sender AddressTuple, rbfInfo RBFConfig) (VinType, error) {
+	if p.MsgTx == nil {
+		return VinType{}, errors.New("wrong cpfp-parent msg-tx is none")
+	}
+	if int(index) >= len(p.MsgTx.TxOut) {
+		return VinType{}, errors.Errorf("wrong cpfp-parent output index=%d out-count=%d", index, len(p.MsgTx.TxOut))
+	}
+	return VinType{
+		OutPoint:    *wire.NewOutPoint(&p.TxHash, index),
+		Sender:      sender,
+		Amount:      p.MsgTx.TxOut[index].Value,
+		RBFInfo:     rbfInfo,
+		Unconfirmed: true,
+	}, nil
+}
+
+// CpfpParam 子交易的参数，子交易把全部输入转到同一个地址，手续费要让父子交易整体达到目标费率
+type CpfpParam struct {
+	Parents       []*CpfpParent  //卡住的父交易，可以是多个
+	VinList       []VinType      //子交易的输入，至少要花费每个父交易的一个输出，也可以追加已确认的输入来补足手续费
+	Target        AddressTuple   //子交易的输出地址，通常就是自己的地址
+	FeeRatePerKb  btcutil.Amount //父子交易整体的目标费率，单位是 聪/千字节，子交易自身也不低于这个费率
+	DustFee       DustFee        //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit     *DustLimit     //灰尘判定规则，为空时使用比特币的规则
+	RelayFeePerKb btcutil.Amount //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	RBFInfo       RBFConfig      //子交易的RBF配置
+	Version       int32          //子交易的版本，为0时自动选择，父交易是 TRUC 交易时子交易也需要是版本3，详见 TrucTxVersion
+}
+
+// CpfpResult 子交易的结果
+type CpfpResult struct {
+	TxParams     *BitcoinTxParams //子交易的参数，可以直接签名
+	Fee          btcutil.Amount   //子交易的手续费
+	PackageFee   btcutil.Amount   //父子交易的手续费之和
+	PackageVSize int              //父子交易的 v-size 之和
+}
+
+// BuildCpfpTx 拼出子交易，子交易的手续费是 max(整体需要的手续费 - 父交易已交的手续费, 子交易自身需要的手续费)
+// 当父交易的手续费已经足够时，子交易只需交自身的手续费
+func BuildCpfpTx(param *CpfpParam, netParams *chaincfg.Params) (*CpfpResult, error) {
+	if len(param.Parents) == 0 {
+		return nil, errors.New("wrong cpfp parents is empty")
+	}
+	var parentsVSize int
+	var parentsFee btcutil.Amount
+	for _, parent := range param.Parents {
+		if !spendsTxOutput(param.VinList, parent.TxHash) {
+			return nil, errors.Errorf("wrong cpfp vin-list not spend parent tx=%s", parent.TxHash)
+		}
+		parentsVSize += parent.VSize
+		parentsFee += parent.Fee
+	}
+
+	pkScript, err := param.Target.GetPkScript(netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong target.address->pk-script")
+	}
+	var inputAmount btcutil.Amount
+	for _, vin := range param.VinList {
+		inputAmount += btcutil.Amount(vin.Amount)
+	}
+
+	txParams := &BitcoinTxParams{
+		VinList: append([]VinType{}, param.VinList...),
+		OutList: []OutType{{
+			Target: param.Target,
+			Amount: int64(inputAmount), //先假设全部转出，以计算手续费
+		}},
+		RBFInfo: param.RBFInfo,
+		Version: param.Version,
+	}
+	childVSize, err := txParams.EstimateTxSize(netParams, NewNoChange())
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong estimate-tx-size")
+	}
+	packageFee := btcutil.Amount(feeForVSizeCeil(param.FeeRatePerKb, parentsVSize+childVSize))
+
+	//和 BuildSweepTx 相同，狗狗币的软灰尘费和输出数量有关，因此在扣掉手续费以后需要再算一遍
+	var fee btcutil.Amount
+	for idx := 0; idx < 2; idx++ {
+		ownFee, err := txParams.EstimateTxFee(netParams, NewNoChange(), param.FeeRatePerKb, param.DustFee)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-tx-fee")
+		}
+		outputs, err := txParams.GetOutputs(netParams)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong get-outputs")
+		}
+		fee = max(packageFee-parentsFee+param.DustFee.SumExtraDustFee(outputs), ownFee)
+		txParams.OutList[0].Amount = int64(inputAmount - fee)
+	}
+
+	amount := inputAmount - fee
+	if amount <= 0 || param.GetDustLimit().IsDustOutput(wire.NewTxOut(int64(amount), pkScript), param.GetRelayFeePerKb()) {
+		return nil, &SweepDustError{
+			InputAmount: inputAmount,
+			Fee:         fee,
+			Amount:      amount,
+		}
+	}
+	return &CpfpResult{
+		TxParams:     txParams,
+		Fee:          fee,
+		PackageFee:   parentsFee + fee,
+		PackageVSize: parentsVSize + childVSize,
+	}, nil
+}
+
+// GetDustLimit 获得灰尘判定规则，当没有设置时使用比特币的规则
+func (param *CpfpParam) GetDustLimit() *DustLimit {
+	if param.DustLimit != nil {
+		return param.DustLimit
+	}
+	return NewDustLimit()
+}
+
+// GetRelayFeePerKb 获得判定灰尘时使用的中继费率
+func (param *CpfpParam) GetRelayFeePerKb() btcutil.Amount {
+	if param.RelayFeePerKb > 0 {
+		return param.RelayFeePerKb
+	}
+	return txrules.DefaultRelayFeePerKb
+}
+
+// spendsTxOutput 输入列表里是否有花费这个交易的输出
+func spendsTxOutput(vinList []VinType, txHash chainhash.Hash) bool {
+	for _, vin := range vinList {
+		if vin.OutPoint.Hash == txHash {
+			return true
+		}
+	}
+	return false
+}
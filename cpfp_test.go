@@ -0,0 +1,100 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCpfpTx(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	parentTx, _, changeIndex := caseNewBumpFeeOrigTx(t, 1234, 1000) //父交易的费率很低
+
+	preMap := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		*MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2): NewSenderAmountUtxo(NewAddressTuple(senderAddress), 13089),
+	})
+	parent, err := NewCpfpParentFromMsgTx(parentTx, preMap)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(141), parent.Fee)
+
+	vin, err := parent.NewVin(uint32(changeIndex), *NewAddressTuple(senderAddress), *NewRBFActive())
+	require.NoError(t, err)
+	require.True(t, vin.Unconfirmed)
+
+	res, err := BuildCpfpTx(&CpfpParam{
+		Parents:      []*CpfpParent{parent},
+		VinList:      []VinType{vin},
+		Target:       *NewAddressTuple(senderAddress),
+		FeeRatePerKb: 10000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}, &netParams)
+	require.NoError(t, err)
+	require.Equal(t, res.Fee, res.TxParams.GetFee())
+	require.Equal(t, parent.Fee+res.Fee, res.PackageFee)
+	//父子交易整体达到目标费率
+	require.GreaterOrEqual(t, int64(res.PackageFee)*1000, int64(10000)*int64(res.PackageVSize))
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	t.Log("child-fee:", res.Fee, "package-fee:", res.PackageFee, "package-v-size:", res.PackageVSize)
+}
+
+func TestBuildCpfpTx_ParentFeeEnough(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	parentHash := MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 0).Hash
+	param := &CpfpParam{
+		Parents: []*CpfpParent{NewCpfpParent(parentHash, 141, 5000)}, //父交易的手续费已经很高
+		VinList: []VinType{
+			{
+				OutPoint:    *wire.NewOutPoint(&parentHash, 1),
+				Sender:      *NewAddressTuple(senderAddress),
+				Amount:      10000,
+				Unconfirmed: true,
+			},
+		},
+		Target:       *NewAddressTuple(senderAddress),
+		FeeRatePerKb: 2000,
+	}
+	res, err := BuildCpfpTx(param, &netParams)
+	require.NoError(t, err)
+
+	ownFee, err := res.TxParams.EstimateTxFee(&netParams, NewNoChange(), 2000, NewDustFee())
+	require.NoError(t, err)
+	require.Equal(t, ownFee, res.Fee) //只交子交易自身的手续费
+}
+
+func TestBuildCpfpTx_NotSpendParent(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	parentHash := MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 0).Hash
+	_, err := BuildCpfpTx(&CpfpParam{
+		Parents: []*CpfpParent{NewCpfpParent(parentHash, 141, 141)},
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4900,
+			},
+		},
+		Target:       *NewAddressTuple(senderAddress),
+		FeeRatePerKb: 2000,
+	}, &netParams)
+	require.Error(t, err)
+	t.Log(err)
+}
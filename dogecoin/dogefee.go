@@ -0,0 +1,29 @@
+package dogecoin
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+const (
+	// MinRelayFeePerKb 最低中继费率，单位是 聪/千字节，详见 https://github.com/dogecoin/dogecoin/blob/master/doc/fee-recommendation.md
+	MinRelayFeePerKb = 100000 // The minimum relay fee is 0.001 DOGE/kB, transactions paying less than this are not relayed.
+
+	// RecommendedFeePerKb 推荐费率，单位是 聪/千字节
+	RecommendedFeePerKb = 1000000 // The recommended fee is 0.01 DOGE/kB.
+
+	// MaxFeeRatePerKb 签名前允许的最大费率，单位是 聪/千字节，是推荐费率的100倍，超过时多半是把找零写错了
+	MaxFeeRatePerKb = 100000000 // 1 DOGE/kB
+
+	// MaxFee 签名前允许的最大手续费，和狗狗币钱包的 -maxtxfee 默认值相同
+	MaxFee = 10000000000 // 100 DOGE
+)
+
+// IsDogeNet 判断是不是狗狗币的网络，比较网络的魔数即可
+func IsDogeNet(netParams *chaincfg.Params) bool {
+	switch netParams.Net {
+	case MainNetParams.Net, TestNetParams.Net, RegressionNetParams.Net:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,29 @@
+package dogecoin
+
+import (
+	"github.com/yyle88/gobtcsign/internal/policies"
+)
+
+type PolicyRules = policies.Rules
+
+// NewDogePolicyRules 狗狗币节点的标准规则，狗狗币没有隔离见证，交易版本最大是2，灰尘和手续费的规则也和比特币不同
+// 具体参考链接在
+// https://github.com/dogecoin/dogecoin/blob/master/src/policy/policy.h
+func NewDogePolicyRules() *PolicyRules {
+	return &PolicyRules{
+		MinTxVersion:          1,
+		MaxTxVersion:          2,
+		MaxTxWeight:           400000, //狗狗币限制的是交易大小 100000 字节，没有见证时重量就是大小的4倍
+		MaxSigOpsCost:         16000,  //狗狗币限制的是 4000 个签名操作，按重量计算就是4倍
+		MaxSigScriptSize:      1650,
+		MaxDataCarrierSize:    80,
+		MaxDataCarrierOutputs: 1,
+		AllowWitness:          false,
+		AllowTaproot:          false,
+		AllowBareMultiSig:     true,
+		MinRelayFeePerKb:      MinRelayFeePerKb,
+		DustLimit:             NewDogeDustLimit(),
+		DustFee:               NewDogeDustFee(),
+		RequireRBF:            false,
+	}
+}
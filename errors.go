@@ -0,0 +1,109 @@
+package gobtcsign
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pkg/errors"
+)
+
+// 这些错误可以使用 errors.Is 判断，返回时通常会附带更多的信息，因此不要比较错误的文本
+var (
+	ErrUnsupportedAddressType  = errors.New("UNSUPPORTED ADDRESS TYPE")   //不支持的地址类型，重试也没用
+	ErrAddressPkScriptMismatch = errors.New("address-pk-script-mismatch") //地址和脚本都填写了，但是两者不匹配
+	ErrNoPkScriptNoAddress     = errors.New("no-pk-script-no-address")    //地址和脚本都没有填写
+	ErrInsufficientFunds       = errors.New("insufficient-funds")         //余额不足，详见 InsufficientFundsError
+	ErrDustOutput              = errors.New("dust-output")                //输出是灰尘，SweepDustError 也能匹配它
+	ErrInputIndex              = errors.New("input-index-out-of-range")   //输入的位置超出范围，详见 InputIndexError
+	ErrVerifySign              = errors.New("verify-sign-failed")         //验签失败，详见 VerifyError
+	ErrNonStandard             = errors.New("non-standard")               //不满足节点的标准规则，详见 PolicyError
+	ErrAmbiguousAddress        = errors.New("ambiguous-address")          //地址同时属于多个网络，详见 AmbiguousAddressError
+	ErrNoAddressPkScript       = errors.New("no-address-pk-script")       //脚本没有对应的地址，比如非标准脚本、OP_RETURN、P2PK 和裸多签
+	ErrOutputIndex             = errors.New("output-index-out-of-range")  //前置输出的位置超出范围，详见 OutputIndexError
+	ErrMaxBatchTxCount         = errors.New("max-batch-tx-count-reached") //批量转账拼出的交易个数达到上限，详见 BatchPayoutResult.UnpaidReason
+	ErrMaxAncestorCount        = errors.New("max-ancestor-count-reached") //花费未确认的UTXO时超出节点的祖先或者后代交易个数上限，详见 BatchPayoutParam.MaxAncestorCount
+	ErrFeeGuard                = errors.New("fee-guard-rejected")         //手续费是负数或者过高，或者找零转到未知的地址，详见 FeeGuard
+	ErrAnchorNotKeyless        = errors.New("anchor-input-not-keyless")   //P2A输入带了解锁脚本或者见证，它包在 VerifyError 里
+	ErrInputOutMissing         = errors.New("input-out-missing")          //某个输入的前置输出是空的，详见 checkInputOuts
+	ErrNotReplaceable          = errors.New("tx-not-replaceable")         //原交易没有声明可替换，详见 NotReplaceableError
+	ErrMsgTxMismatch           = errors.New("msg-tx-param-mismatch")      //交易和参数不一致，详见 CheckMsgTxParam，输出脚本不一致时是 ErrAddressPkScriptMismatch
+)
+
+// InsufficientFundsError 余额不足时返回这个错误，通常需要等待更多的UTXO或者降低费率，调用方可以使用 errors.As 判断
+type InsufficientFundsError struct {
+	Available btcutil.Amount //可用的数量
+	Required  btcutil.Amount //需要的数量
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient-funds available=%d required=%d", e.Available, e.Required)
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// InputIndexError 输入的位置超出交易的输入个数时返回这个错误
+type InputIndexError struct {
+	Index int //输入的位置
+	Count int //交易的输入个数
+}
+
+func (e *InputIndexError) Error() string {
+	return fmt.Sprintf("wrong input index=%d tx-in count=%d", e.Index, e.Count)
+}
+
+func (e *InputIndexError) Is(target error) bool {
+	return target == ErrInputIndex
+}
+
+// OutputIndexError 前置输出的位置超出前置交易的输出个数时返回这个错误，通常是 outpoint 写错了
+type OutputIndexError struct {
+	TxHash string //前置交易的哈希
+	Index  uint32 //输出的位置
+	Count  int    //前置交易的输出个数
+}
+
+func (e *OutputIndexError) Error() string {
+	return fmt.Sprintf("wrong output index=%d tx=%s vout count=%d", e.Index, e.TxHash, e.Count)
+}
+
+func (e *OutputIndexError) Is(target error) bool {
+	return target == ErrOutputIndex
+}
+
+// VerifyError 验签失败时返回这个错误，包含失败的输入位置和脚本引擎的错误码，调用方可以使用 errors.As 判断
+// 错误码的含义见 txscript.ErrorCode，比如 txscript.ErrNullFail 通常表示签名不对（签名的私钥或者输入的数量不对），当不是脚本错误时是 txscript.ErrInternal
+type VerifyError struct {
+	Index     int                //验签失败的输入位置
+	ErrorCode txscript.ErrorCode //脚本引擎的错误码，不是脚本引擎报的错时是零值 txscript.ErrInternal
+	Err       error              //原始的错误
+	reason    string
+}
+
+func newVerifyError(idx int, err error, reason string) *VerifyError {
+	var errorCode = txscript.ErrInternal
+	var scriptErr txscript.Error
+	if errors.As(err, &scriptErr) {
+		errorCode = scriptErr.ErrorCode
+	}
+	return &VerifyError{
+		Index:     idx,
+		ErrorCode: errorCode,
+		Err:       err,
+		reason:    reason,
+	}
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s. index=%d: %s", e.reason, e.Index, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+func (e *VerifyError) Is(target error) bool {
+	return target == ErrVerifySign
+}
@@ -0,0 +1,137 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressTuple_GetPkScript_Errors(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	_, err := (&AddressTuple{}).GetPkScript(&netParams)
+	require.ErrorIs(t, err, ErrNoPkScriptNoAddress)
+	require.Equal(t, "no-pk-script-no-address", err.Error())
+
+	tuple := &AddressTuple{
+		Address:  "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap",
+		PkScript: MustGetPkScript(MustNewAddress("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx", &netParams)),
+	}
+	_, err = tuple.GetPkScript(&netParams)
+	require.ErrorIs(t, err, ErrAddressPkScriptMismatch)
+	require.ErrorIs(t, tuple.VerifyMatch(&netParams), ErrAddressPkScriptMismatch)
+
+	_, err = CalculateChangePkScriptSize([]byte{txscript.OP_TRUE})
+	require.ErrorIs(t, err, ErrUnsupportedAddressType)
+}
+
+func TestSign_UnsupportedAddressType(t *testing.T) {
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092"
+
+	netParams := chaincfg.TestNet3Params
+
+	address, err := btcutil.NewAddressScriptHash([]byte{txscript.OP_TRUE}, &netParams)
+	require.NoError(t, err)
+
+	signParam, err := caseNewTxVersionParam(0).CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	err = Sign(address.EncodeAddress(), privateKeyHex, signParam)
+	require.ErrorIs(t, err, ErrUnsupportedAddressType)
+}
+
+func TestChangeBuilder_Build_InsufficientFundsError(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(0)
+	param.OutList[0].Amount = 13000
+
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+	_, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var fundsErr *InsufficientFundsError
+	require.True(t, errors.As(err, &fundsErr))
+	require.Equal(t, btcutil.Amount(89), fundsErr.Available)
+	require.Greater(t, fundsErr.Required, fundsErr.Available)
+}
+
+func TestSweepDustError_Is(t *testing.T) {
+	var err error = errors.WithStack(&SweepDustError{InputAmount: 500, Fee: 300, Amount: 200})
+	require.ErrorIs(t, err, ErrDustOutput)
+	require.NotErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestVerifyError(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(0)
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+
+	//隔离见证的签名包含了输入的数量，数量不对时验签失败
+	param.VinList[0].Amount++
+	err = param.VerifyMsgTxSign(signParam.MsgTx, &netParams)
+	require.ErrorIs(t, err, ErrVerifySign)
+
+	var verifyErr *VerifyError
+	require.True(t, errors.As(err, &verifyErr))
+	require.Equal(t, 0, verifyErr.Index)
+	require.Equal(t, txscript.ErrNullFail, verifyErr.ErrorCode) //签名不对而且不是空的签名
+	require.Contains(t, err.Error(), "wrong check-sign-vm-execute. index=0")
+	t.Log(err)
+}
+
+func TestInputIndexError(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	receiverKey, refundKey := caseNewHtlcKeys(t)
+	contract := &HtlcContract{
+		PaymentHash:      NewHtlcPaymentHash([]byte("preimage")),
+		ReceiverPubKey:   receiverKey.PubKey().SerializeCompressed(),
+		RefundPubKey:     refundKey.PubKey().SerializeCompressed(),
+		AbsoluteLockTime: 100,
+	}
+
+	signParam, err := caseNewTxVersionParam(0).CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	err = SignHtlcRefund(signParam, 5, contract, refundKey)
+	require.ErrorIs(t, err, ErrInputIndex)
+
+	var indexErr *InputIndexError
+	require.True(t, errors.As(err, &indexErr))
+	require.Equal(t, 5, indexErr.Index)
+	require.Equal(t, 1, indexErr.Count)
+}
+
+func TestInputIndexError_InputOutsLength(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	_, signParam := caseNewVerifyReportSignParam(t, senderAddress, privateKeyHex, &netParams)
+
+	//前置输出比输入少
+	err := VerifySign(signParam.MsgTx, signParam.InputOuts[:1], nil, nil)
+	require.ErrorIs(t, err, ErrInputIndex)
+	var indexErr *InputIndexError
+	require.True(t, errors.As(err, &indexErr))
+	require.Equal(t, len(signParam.MsgTx.TxIn)-1, indexErr.Index)
+	require.Equal(t, 1, indexErr.Count)
+
+	_, err = VerifySignReport(signParam.MsgTx, signParam.InputOuts[:1], 1)
+	require.ErrorIs(t, err, ErrInputIndex)
+
+	err = VerifySignV4(signParam.MsgTx, [][]byte{signParam.InputOuts[0].PkScript}, nil)
+	require.ErrorIs(t, err, ErrInputIndex)
+}
@@ -0,0 +1,173 @@
+package gobtcsign
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+const (
+	DefaultMaxFeeRatePerKb btcutil.Amount = 10000000 //默认的最大费率 0.1 BTC/kvB，和 Bitcoin Core 的 sendrawtransaction 的 maxfeerate 默认值相同
+	DefaultMaxFee          btcutil.Amount = 10000000 //默认的最大手续费 0.1 BTC，和 Bitcoin Core 钱包的 -maxtxfee 默认值相同
+)
+
+// FeeGuard 签名前的安全检查，避免手算找零时写错数字，把整个UTXO都送给矿工
+// 它会检查手续费不能是负数、手续费和费率不能过高，以及找零输出只能转到已知的地址
+// CreateTxSignParams 和 Sign 都会执行这个检查，为空时使用默认的 NewFeeGuard()，确实需要跳过时使用 NewFeeGuardDisabled()
+type FeeGuard struct {
+	MaxFeeRatePerKb btcutil.Amount //最大费率，单位是 聪/千字节，为0时使用 DefaultMaxFeeRatePerKb，狗狗币使用 dogecoin.MaxFeeRatePerKb
+	MaxFee          btcutil.Amount //最大手续费，为0时使用 DefaultMaxFee，狗狗币使用 dogecoin.MaxFee
+	ChangeTargets   []AddressTuple //已知的找零地址，设置以后找零输出只能转到这些地址，标记为找零（OutType.IsChange）或者转回输入地址的输出都算找零
+	Disabled        bool           //跳过全部检查，只有在明确知道自己在做什么时才使用
+}
+
+// NewFeeGuard 使用默认限制的安全检查
+func NewFeeGuard() *FeeGuard {
+	return &FeeGuard{}
+}
+
+// NewFeeGuardDisabled 跳过安全检查
+func NewFeeGuardDisabled() *FeeGuard {
+	return &FeeGuard{Disabled: true}
+}
+
+// GetMaxFeeRatePerKb 获得最大费率，狗狗币的币值较小，默认值和比特币不同
+func (guard *FeeGuard) GetMaxFeeRatePerKb(netParams *chaincfg.Params) btcutil.Amount {
+	if guard.MaxFeeRatePerKb > 0 {
+		return guard.MaxFeeRatePerKb
+	}
+	if dogecoin.IsDogeNet(netParams) {
+		return dogecoin.MaxFeeRatePerKb
+	}
+	return DefaultMaxFeeRatePerKb
+}
+
+// GetMaxFee 获得最大手续费，狗狗币的币值较小，默认值和比特币不同
+func (guard *FeeGuard) GetMaxFee(netParams *chaincfg.Params) btcutil.Amount {
+	if guard.MaxFee > 0 {
+		return guard.MaxFee
+	}
+	if dogecoin.IsDogeNet(netParams) {
+		return dogecoin.MaxFee
+	}
+	return DefaultMaxFee
+}
+
+// CheckFee 检查手续费，vSize 是交易的 v-size，签名前可以使用 EstimateTxSize 预估
+func (guard *FeeGuard) CheckFee(fee btcutil.Amount, vSize int, netParams *chaincfg.Params) error {
+	if guard.Disabled {
+		return nil
+	}
+	if fee < 0 {
+		return errors.WithMessagef(ErrFeeGuard, "negative fee=%d, outputs exceed inputs", fee)
+	}
+	if maxFee := guard.GetMaxFee(netParams); fee > maxFee {
+		return errors.WithMessagef(ErrFeeGuard, "absurd fee=%d > max-fee=%d", fee, maxFee)
+	}
+	if maxRate := guard.GetMaxFeeRatePerKb(netParams); vSize > 0 && int64(fee)*1000 > int64(maxRate)*int64(vSize) {
+		return errors.WithMessagef(ErrFeeGuard, "absurd fee-rate fee=%d v-size=%d > max-fee-rate=%d", fee, vSize, maxRate)
+	}
+	return nil
+}
+
+// checkChangeTargets 检查找零输出是否转到已知的找零地址，没有配置找零地址时不检查
+// 手算找零时通常不会设置 IsChange，因此转回任意输入地址的输出也当作找零检查
+func (guard *FeeGuard) checkChangeTargets(vinList []VinType, outList []OutType, netParams *chaincfg.Params) error {
+	if guard.Disabled || len(guard.ChangeTargets) == 0 {
+		return nil
+	}
+	var knownScripts = make([][]byte, 0, len(guard.ChangeTargets))
+	for _, target := range guard.ChangeTargets {
+		pkScript, err := target.GetPkScript(netParams)
+		if err != nil {
+			return errors.WithMessage(err, "wrong change-target->pk-script")
+		}
+		knownScripts = append(knownScripts, pkScript)
+	}
+	var senderScripts = make([][]byte, 0, len(vinList))
+	for _, vin := range vinList {
+		pkScript, err := vin.Sender.GetPkScript(netParams)
+		if err != nil {
+			return errors.WithMessage(err, "wrong sender.address->pk-script")
+		}
+		senderScripts = append(senderScripts, pkScript)
+	}
+	for idx, output := range outList {
+		pkScript, err := output.GetPkScript(netParams)
+		if err != nil {
+			return errors.WithMessage(err, "wrong target.address->pk-script")
+		}
+		if !output.IsChange && !containsPkScript(senderScripts, pkScript) {
+			continue
+		}
+		if !containsPkScript(knownScripts, pkScript) {
+			return errors.WithMessagef(ErrFeeGuard, "change output %d pk-script=%x is not a known change address", idx, pkScript)
+		}
+	}
+	return nil
+}
+
+// GetFeeGuard 获得签名前的安全检查，没有设置时使用默认的
+func (param *BitcoinTxParams) GetFeeGuard() *FeeGuard {
+	if param.FeeGuard != nil {
+		return param.FeeGuard
+	}
+	return NewFeeGuard()
+}
+
+// CheckFeeGuard 执行签名前的安全检查，CreateTxSignParams 会调用它
+func (param *BitcoinTxParams) CheckFeeGuard(netParams *chaincfg.Params) error {
+	guard := param.GetFeeGuard()
+	if guard.Disabled {
+		return nil
+	}
+	vSize, err := EstimateTxSize(param, netParams, NewNoChange())
+	if err != nil {
+		return errors.WithMessage(err, "wrong estimate-tx-size")
+	}
+	if err := guard.CheckFee(param.GetFee(), vSize, netParams); err != nil {
+		return err
+	}
+	return guard.checkChangeTargets(param.VinList, param.OutList, netParams)
+}
+
+// CheckFeeGuard 对待签名的交易执行安全检查，Sign 会调用它，这里没有找零的标记，因此只检查手续费
+func (param *SignParam) CheckFeeGuard() error {
+	guard := param.FeeGuard
+	if guard == nil {
+		guard = NewFeeGuard()
+	}
+	if guard.Disabled {
+		return nil
+	}
+	if err := checkInputOuts(param.MsgTx, param.InputOuts); err != nil {
+		return err
+	}
+	var fee int64
+	var scripts = make([][]byte, 0, len(param.MsgTx.TxIn))
+	for idx := range param.MsgTx.TxIn {
+		fee += param.InputOuts[idx].Value
+		scripts = append(scripts, param.InputOuts[idx].PkScript)
+	}
+	for _, txOut := range param.MsgTx.TxOut {
+		fee -= txOut.Value
+	}
+	vSize, err := EstimateSize(scripts, param.MsgTx.TxOut, NewNoChange())
+	if err != nil {
+		return errors.WithMessage(err, "wrong estimate-size")
+	}
+	return guard.CheckFee(btcutil.Amount(fee), vSize, param.NetParams)
+}
+
+// containsPkScript 脚本列表里是否有这个脚本
+func containsPkScript(pkScripts [][]byte, pkScript []byte) bool {
+	for _, one := range pkScripts {
+		if bytes.Equal(one, pkScript) {
+			return true
+		}
+	}
+	return false
+}
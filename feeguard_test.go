@@ -0,0 +1,121 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func TestFeeGuard_CheckFee(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	guard := NewFeeGuard()
+	require.NoError(t, guard.CheckFee(1000, 200, &netParams))
+	require.ErrorIs(t, guard.CheckFee(-1, 200, &netParams), ErrFeeGuard)
+	require.ErrorIs(t, guard.CheckFee(DefaultMaxFee+1, 1000000, &netParams), ErrFeeGuard)
+	//手续费不多，但是交易很小，费率就过高了
+	require.ErrorIs(t, guard.CheckFee(3000000, 200, &netParams), ErrFeeGuard)
+
+	//狗狗币的默认限制更宽
+	require.NoError(t, guard.CheckFee(3000000, 200, &dogecoin.TestNetParams))
+	require.ErrorIs(t, guard.CheckFee(dogecoin.MaxFee+1, 10000000, &dogecoin.TestNetParams), ErrFeeGuard)
+
+	//自定义限制
+	guard = &FeeGuard{MaxFee: 500}
+	require.ErrorIs(t, guard.CheckFee(1000, 200, &netParams), ErrFeeGuard)
+
+	//跳过检查
+	require.NoError(t, NewFeeGuardDisabled().CheckFee(-1, 200, &netParams))
+}
+
+func TestBitcoinTxParams_FeeGuard(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	//输出比输入多
+	param := caseNewTxVersionParam(2)
+	param.OutList[0].Amount = 13089 + 1
+	_, err := param.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrFeeGuard)
+	t.Log(err)
+
+	//忘了找零，整个UTXO都给了矿工
+	param = caseNewTxVersionParam(2)
+	param.VinList[0].Amount = 50000000
+	_, err = param.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrFeeGuard)
+	t.Log(err)
+
+	//明确跳过检查时可以拼接交易
+	param.FeeGuard = NewFeeGuardDisabled()
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.True(t, signParam.FeeGuard.Disabled)
+}
+
+func TestBitcoinTxParams_FeeGuard_ChangeTargets(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(2)
+	param.OutList[0].Amount = 1234
+	param.FeeGuard = &FeeGuard{ChangeTargets: []AddressTuple{*NewAddressTuple(senderAddress)}}
+
+	//找零到已知的地址
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	require.True(t, res.TxParams.OutList[res.ChangeIndex].IsChange)
+	_, err = res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+
+	//找零到未知的地址
+	change = &ChangeTo{AddressX: MustNewAddress("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx", &netParams)}
+	res, err = param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	_, err = res.TxParams.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrFeeGuard)
+	t.Log(err)
+}
+
+func TestBitcoinTxParams_FeeGuard_ChangeTargets_HandMade(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	//手算的找零没有 IsChange 标记，转回输入地址的输出也要是已知的找零地址
+	param := caseNewTxVersionParam(2)
+	param.OutList[0].Amount = 1234
+	param.OutList = append(param.OutList, OutType{Target: *NewAddressTuple(senderAddress), Amount: 10855})
+	param.FeeGuard = &FeeGuard{ChangeTargets: []AddressTuple{*NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx")}}
+	_, err := param.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrFeeGuard)
+	t.Log(err)
+
+	param.FeeGuard = &FeeGuard{ChangeTargets: []AddressTuple{*NewAddressTuple(senderAddress)}}
+	_, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+}
+
+func TestSign_FeeGuard(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	signParam, err := caseNewTxVersionParam(2).CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+
+	//拼接以后又改了输出的数量
+	signParam.MsgTx.TxOut[0].Value = 13089 + 1
+	require.ErrorIs(t, Sign(senderAddress, privateKeyHex, signParam), ErrFeeGuard)
+
+	signParam.MsgTx.TxOut[0].Value = 12000
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+
+	//前置输出比输入少
+	signParam.InputOuts = nil
+	require.ErrorIs(t, signParam.CheckFeeGuard(), ErrInputIndex)
+}
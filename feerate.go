@@ -0,0 +1,169 @@
+package gobtcsign
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+// FeeRate 费率，内部统一使用 聪/千字节（sat/kvB）作为单位，也就是 EstimateTxFee 的 feeRatePerKb 参数的含义
+// 成员是不导出的，只能通过带单位的构造函数得到，以避免把 sat/vB 的数当作 sat/kvB 传进去
+type FeeRate struct {
+	satPerKvB btcutil.Amount
+}
+
+// NewFeeRateFromSatPerKvB 通过 聪/千字节 得到费率，这就是 EstimateTxFee 使用的单位
+func NewFeeRateFromSatPerKvB(satPerKvB btcutil.Amount) FeeRate {
+	return FeeRate{satPerKvB: satPerKvB}
+}
+
+// NewFeeRateFromSatPerVByte 通过 聪/字节 得到费率，这是各种区块浏览器和费率接口使用的单位，允许小数比如 1.5 sat/vB
+// 先四舍五入到 0.001 聪/千字节 以消除浮点数的误差（比如 1.1*1000 会得到 1100.0000000000002），再向上取整，保证费率不低于给定值
+func NewFeeRateFromSatPerVByte(satPerVByte float64) (FeeRate, error) {
+	if math.IsNaN(satPerVByte) || math.IsInf(satPerVByte, 0) || satPerVByte < 0 {
+		return FeeRate{}, errors.Errorf("wrong sat-per-v-byte=%v", satPerVByte)
+	}
+	satPerKvB := math.Ceil(math.Round(satPerVByte*1000*1000) / 1000)
+	if satPerKvB > btcutil.MaxSatoshi {
+		return FeeRate{}, errors.Errorf("wrong sat-per-v-byte=%v is too large", satPerVByte)
+	}
+	return FeeRate{satPerKvB: btcutil.Amount(satPerKvB)}, nil
+}
+
+// NewFeeRateFromCoinPerKb 通过 币/千字节 得到费率，狗狗币的费率通常使用 DOGE/kB 表示，比特币节点的 estimatesmartfee 返回的是 BTC/kB
+// 币的数量按照 btcutil.NewAmount 的规则四舍五入到聪，这和节点解析 JSON 数量的规则相同
+func NewFeeRateFromCoinPerKb(coinPerKb float64) (FeeRate, error) {
+	if coinPerKb < 0 {
+		return FeeRate{}, errors.Errorf("wrong coin-per-kb=%v", coinPerKb)
+	}
+	satPerKvB, err := btcutil.NewAmount(coinPerKb)
+	if err != nil {
+		return FeeRate{}, errors.WithMessagef(err, "wrong coin-per-kb=%v", coinPerKb)
+	}
+	return FeeRate{satPerKvB: satPerKvB}, nil
+}
+
+// SatPerKvB 费率，单位是 聪/千字节，可以传给 EstimateTxFee 等使用 feeRatePerKb 的函数
+func (rate FeeRate) SatPerKvB() btcutil.Amount {
+	return rate.satPerKvB
+}
+
+// SatPerVByte 费率，单位是 聪/字节，可能是小数
+func (rate FeeRate) SatPerVByte() float64 {
+	return float64(rate.satPerKvB) / 1000
+}
+
+// CoinPerKb 费率，单位是 币/千字节
+func (rate FeeRate) CoinPerKb() float64 {
+	return rate.satPerKvB.ToBTC()
+}
+
+// FeeForVSize 计算这个大小的交易需要的手续费，取整规则和 txrules.FeeForSerializeSize 相同，即向下取整，但结果为0时使用一千字节的费用
+func (rate FeeRate) FeeForVSize(vSize int) btcutil.Amount {
+	return txrules.FeeForSerializeSize(rate.satPerKvB, vSize)
+}
+
+// IsZero 费率是不是0
+func (rate FeeRate) IsZero() bool {
+	return rate.satPerKvB == 0
+}
+
+// Less 费率是否低于另一个费率
+func (rate FeeRate) Less(other FeeRate) bool {
+	return rate.satPerKvB < other.satPerKvB
+}
+
+// Max 返回两个费率里较高的那个，通常用于把费率抬高到最低中继费率
+func (rate FeeRate) Max(other FeeRate) FeeRate {
+	if rate.Less(other) {
+		return other
+	}
+	return rate
+}
+
+func (rate FeeRate) String() string {
+	return strconv.FormatFloat(rate.SatPerVByte(), 'f', -1, 64) + " sat/vB"
+}
+
+// MinRelayFeeRate 获得链的最低中继费率，低于它的交易不会被节点转发
+// 比特币是 1 sat/vB，狗狗币是 0.001 DOGE/kB
+func MinRelayFeeRate(netParams *chaincfg.Params) FeeRate {
+	if dogecoin.IsDogeNet(netParams) {
+		return NewFeeRateFromSatPerKvB(dogecoin.MinRelayFeePerKb)
+	}
+	return NewFeeRateFromSatPerKvB(txrules.DefaultRelayFeePerKb)
+}
+
+// CheckMinRelayFeeRate 检查费率是否低于链的最低中继费率
+func CheckMinRelayFeeRate(rate FeeRate, netParams *chaincfg.Params) error {
+	if minRate := MinRelayFeeRate(netParams); rate.Less(minRate) {
+		return errors.Errorf("wrong fee-rate=%s < min-relay-fee-rate=%s", rate, minRate)
+	}
+	return nil
+}
+
+// EstimateTxFeeWithRate 和 EstimateTxFee 相同，只是使用带单位的费率，而且当费率低于链的最低中继费率时返回错误
+func EstimateTxFeeWithRate(param *BitcoinTxParams, netParams *chaincfg.Params, change *ChangeTo, feeRate FeeRate, dustFee DustFee) (btcutil.Amount, error) {
+	if err := CheckMinRelayFeeRate(feeRate, netParams); err != nil {
+		return 0, err
+	}
+	return EstimateTxFee(param, netParams, change, feeRate.SatPerKvB(), dustFee)
+}
+
+func (param *BitcoinTxParams) EstimateTxFeeWithRate(netParams *chaincfg.Params, change *ChangeTo, feeRate FeeRate, dustFee DustFee) (btcutil.Amount, error) {
+	return EstimateTxFeeWithRate(param, netParams, change, feeRate, dustFee)
+}
+
+// NewChangeBuilderWithRate 和 NewChangeBuilder 相同，只是使用带单位的费率
+func NewChangeBuilderWithRate(change *ChangeTo, feeRate FeeRate, dustFee DustFee, dustLimit *DustLimit) *ChangeBuilder {
+	return NewChangeBuilder(change, feeRate.SatPerKvB(), dustFee, dustLimit)
+}
+
+// 下面这些参数的费率字段都是 聪/千字节 的，WithFeeRate 使用带单位的费率设置它，返回参数本身以便直接传给对应的函数
+
+// WithFeeRate 使用带单位的费率设置 FeeRatePerKb，详见 CoinSelector
+func (param *CoinSelectParam) WithFeeRate(feeRate FeeRate) *CoinSelectParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
+
+// WithFeeRate 使用带单位的费率设置 FeeRatePerKb，详见 BuildSweepTx
+func (param *SweepParam) WithFeeRate(feeRate FeeRate) *SweepParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
+
+// WithFeeRate 使用带单位的费率设置 FeeRatePerKb，详见 PlanConsolidation
+func (param *ConsolidateParam) WithFeeRate(feeRate FeeRate) *ConsolidateParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
+
+// WithFeeRate 使用带单位的费率设置 FeeRatePerKb，详见 BuildBatchPayout
+func (param *BatchPayoutParam) WithFeeRate(feeRate FeeRate) *BatchPayoutParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
+
+// WithFeeRate 使用带单位的费率设置 FeeRatePerKb，详见 BumpFee
+func (param *BumpFeeParam) WithFeeRate(feeRate FeeRate) *BumpFeeParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
+
+// WithFeeRate 使用带单位的费率设置父子交易整体的目标费率 FeeRatePerKb，详见 BuildCpfpTx
+func (param *CpfpParam) WithFeeRate(feeRate FeeRate) *CpfpParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
+
+// WithFeeRate 使用带单位的费率设置 FeeRatePerKb，详见 BuildCancelTx
+func (param *CancelTxParam) WithFeeRate(feeRate FeeRate) *CancelTxParam {
+	param.FeeRatePerKb = feeRate.SatPerKvB()
+	return param
+}
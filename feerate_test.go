@@ -0,0 +1,120 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func TestNewFeeRateFromSatPerVByte(t *testing.T) {
+	rate, err := NewFeeRateFromSatPerVByte(1.1)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(1100), rate.SatPerKvB()) //不能因为浮点误差变成 1101
+
+	rate, err = NewFeeRateFromSatPerVByte(2.0005)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(2001), rate.SatPerKvB()) //向上取整
+	t.Log(rate)
+
+	_, err = NewFeeRateFromSatPerVByte(-1)
+	require.Error(t, err)
+}
+
+func TestNewFeeRateFromCoinPerKb(t *testing.T) {
+	rate, err := NewFeeRateFromCoinPerKb(0.01) //狗狗币推荐的 0.01 DOGE/kB
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(dogecoin.RecommendedFeePerKb), rate.SatPerKvB())
+	require.Equal(t, 0.01, rate.CoinPerKb())
+	require.Equal(t, float64(1000), rate.SatPerVByte())
+
+	rate, err = NewFeeRateFromCoinPerKb(0.00001234)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(1234), rate.SatPerKvB())
+}
+
+func TestFeeRate_FeeForVSize(t *testing.T) {
+	rate := NewFeeRateFromSatPerKvB(1500)
+	require.Equal(t, btcutil.Amount(211), rate.FeeForVSize(141)) //向下取整
+	require.Equal(t, btcutil.Amount(1500), rate.FeeForVSize(0))  //结果为0时使用一千字节的费用
+}
+
+func TestMinRelayFeeRate(t *testing.T) {
+	require.Equal(t, btcutil.Amount(1000), MinRelayFeeRate(&chaincfg.MainNetParams).SatPerKvB())
+	require.Equal(t, btcutil.Amount(1000), MinRelayFeeRate(&chaincfg.TestNet3Params).SatPerKvB())
+	require.Equal(t, btcutil.Amount(100000), MinRelayFeeRate(&dogecoin.MainNetParams).SatPerKvB())
+	require.Equal(t, btcutil.Amount(100000), MinRelayFeeRate(&dogecoin.TestNetParams).SatPerKvB())
+
+	rate := NewFeeRateFromSatPerKvB(500)
+	require.Error(t, CheckMinRelayFeeRate(rate, &chaincfg.TestNet3Params))
+	require.Equal(t, MinRelayFeeRate(&chaincfg.TestNet3Params), rate.Max(MinRelayFeeRate(&chaincfg.TestNet3Params)))
+}
+
+func TestEstimateTxFeeWithRate(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple("tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"),
+				Amount:   13089,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 1234,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+
+	rate, err := NewFeeRateFromSatPerVByte(2)
+	require.NoError(t, err)
+
+	fee, err := param.EstimateTxFeeWithRate(&netParams, NewNoChange(), rate, NewDustFee())
+	require.NoError(t, err)
+	expected, err := param.EstimateTxFee(&netParams, NewNoChange(), 2000, NewDustFee())
+	require.NoError(t, err)
+	require.Equal(t, expected, fee)
+
+	_, err = param.EstimateTxFeeWithRate(&netParams, NewNoChange(), NewFeeRateFromSatPerKvB(100), NewDustFee())
+	require.Error(t, err)
+	t.Log(err)
+}
+
+func TestParam_WithFeeRate(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	rate, err := NewFeeRateFromSatPerVByte(2.5)
+	require.NoError(t, err)
+
+	require.Equal(t, btcutil.Amount(2500), (&CoinSelectParam{}).WithFeeRate(rate).FeeRatePerKb)
+	require.Equal(t, btcutil.Amount(2500), (&ConsolidateParam{}).WithFeeRate(rate).FeeRatePerKb)
+	require.Equal(t, btcutil.Amount(2500), (&BatchPayoutParam{}).WithFeeRate(rate).FeeRatePerKb)
+	require.Equal(t, btcutil.Amount(2500), (&BumpFeeParam{}).WithFeeRate(rate).FeeRatePerKb)
+	require.Equal(t, btcutil.Amount(2500), (&CpfpParam{}).WithFeeRate(rate).FeeRatePerKb)
+	require.Equal(t, btcutil.Amount(2500), (&CancelTxParam{}).WithFeeRate(rate).FeeRatePerKb)
+
+	//和直接填写 聪/千字节 的费率得到相同的交易
+	sweepParam := &SweepParam{
+		VinList:   caseNewConsolidateVinList(senderAddress, []int64{20000, 30000}),
+		Target:    *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+		DustFee:   NewDustFee(),
+		DustLimit: NewDustLimit(),
+		RBFInfo:   *NewRBFActive(),
+	}
+	txParams, err := BuildSweepTx(sweepParam.WithFeeRate(rate), &netParams)
+	require.NoError(t, err)
+
+	sweepParam.FeeRatePerKb = 2500
+	expected, err := BuildSweepTx(sweepParam, &netParams)
+	require.NoError(t, err)
+	require.Equal(t, expected.GetFee(), txParams.GetFee())
+}
@@ -0,0 +1,188 @@
+package gobtcsign
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+// HtlcContract 哈希时间锁合约，用于 BTC 和 DOGE 之间的原子交换
+// 接收方拿出原像（preimage）就能领取，否则等锁定时间到期以后退款方可以取回
+// 锁定时间二选一：AbsoluteLockTime 使用 OP_CHECKLOCKTIMEVERIFY，RelativeLock 使用 OP_CHECKSEQUENCEVERIFY
+// 脚本是
+//
+//	OP_IF
+//	    OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <payment_hash> OP_EQUALVERIFY <receiver_pub_key>
+//	OP_ELSE
+//	    <lock> OP_CHECKLOCKTIMEVERIFY/OP_CHECKSEQUENCEVERIFY OP_DROP <refund_pub_key>
+//	OP_ENDIF
+//	OP_CHECKSIG
+//
+// 限制原像的长度是32字节，避免两条链对原像长度的限制不同时，一方能领取而另一方不能领取
+type HtlcContract struct {
+	PaymentHash      []byte        //原像的 sha256 哈希，32字节
+	ReceiverPubKey   []byte        //接收方的压缩公钥，33字节，凭原像领取
+	RefundPubKey     []byte        //退款方的压缩公钥，33字节，锁定时间到期后取回
+	AbsoluteLockTime uint32        //绝对锁定时间，区块高度或者时间戳，和 RelativeLock 二选一
+	RelativeLock     *RelativeLock //相对时间锁，从合约的UTXO被确认开始计算，和 AbsoluteLockTime 二选一
+}
+
+// NewHtlcPaymentHash 根据原像计算出合约里的哈希
+func NewHtlcPaymentHash(preimage []byte) []byte {
+	hash := sha256.Sum256(preimage)
+	return hash[:]
+}
+
+// Script 合约的脚本，P2WSH 和 P2SH 里使用的都是这个脚本
+func (c *HtlcContract) Script() ([]byte, error) {
+	if len(c.PaymentHash) != sha256.Size {
+		return nil, errors.Errorf("wrong htlc payment-hash size=%d", len(c.PaymentHash))
+	}
+	for _, pubKey := range [][]byte{c.ReceiverPubKey, c.RefundPubKey} {
+		if len(pubKey) != btcec.PubKeyBytesLenCompressed {
+			return nil, errors.Errorf("wrong htlc pub-key size=%d, need compressed pub-key", len(pubKey))
+		}
+		if _, err := btcec.ParsePubKey(pubKey); err != nil {
+			return nil, errors.WithMessage(err, "wrong htlc pub-key")
+		}
+	}
+	if (c.AbsoluteLockTime == 0) == (c.RelativeLock == nil) {
+		return nil, errors.New("wrong htlc lock, need exactly one of absolute-lock-time and relative-lock")
+	}
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddOp(txscript.OP_IF)
+	builder.AddOp(txscript.OP_SIZE).AddInt64(sha256.Size).AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddOp(txscript.OP_SHA256).AddData(c.PaymentHash).AddOp(txscript.OP_EQUALVERIFY)
+	builder.AddData(c.ReceiverPubKey)
+	builder.AddOp(txscript.OP_ELSE)
+	if c.RelativeLock != nil {
+		builder.AddInt64(int64(c.RelativeLock.GetSequence())).AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+	} else {
+		builder.AddInt64(int64(c.AbsoluteLockTime)).AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+	}
+	builder.AddOp(txscript.OP_DROP)
+	builder.AddData(c.RefundPubKey)
+	builder.AddOp(txscript.OP_ENDIF)
+	builder.AddOp(txscript.OP_CHECKSIG)
+	return builder.Script()
+}
+
+// P2WSHAddress 合约的隔离见证地址，狗狗币不支持隔离见证，因此在狗狗币的网络里会返回错误
+func (c *HtlcContract) P2WSHAddress(netParams *chaincfg.Params) (*btcutil.AddressWitnessScriptHash, error) {
+	if dogecoin.IsDogeNet(netParams) {
+		return nil, errors.Errorf("wrong net=%s not support p2wsh", netParams.Name)
+	}
+	script, err := c.Script()
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong htlc script")
+	}
+	scriptHash := sha256.Sum256(script)
+	return btcutil.NewAddressWitnessScriptHash(scriptHash[:], netParams)
+}
+
+// P2SHAddress 合约的 P2SH 地址，比特币和狗狗币都可以使用
+// 注意 EstimateSize 会把 P2SH 输入当作 P2SH-P2WPKH 计算大小，而花费合约的输入要大得多，花费时需要预留更多的手续费
+func (c *HtlcContract) P2SHAddress(netParams *chaincfg.Params) (*btcutil.AddressScriptHash, error) {
+	script, err := c.Script()
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong htlc script")
+	}
+	return btcutil.NewAddressScriptHash(script, netParams)
+}
+
+// SignHtlcClaim 接收方使用原像领取合约里的钱，签名第 idx 个输入，这个输入需要花费合约的 P2WSH 或者 P2SH 输出
+func SignHtlcClaim(signParam *SignParam, idx int, contract *HtlcContract, privKey *btcec.PrivateKey, preimage []byte) error {
+	if !bytes.Equal(NewHtlcPaymentHash(preimage), contract.PaymentHash) {
+		return errors.New("wrong htlc preimage not match payment-hash")
+	}
+	if !bytes.Equal(privKey.PubKey().SerializeCompressed(), contract.ReceiverPubKey) {
+		return errors.New("wrong htlc private-key not match receiver-pub-key")
+	}
+	return signHtlcInput(signParam, idx, contract, privKey, [][]byte{preimage, {1}})
+}
+
+// SignHtlcRefund 退款方在锁定时间到期后取回合约里的钱，签名第 idx 个输入
+// 使用绝对锁定时间时，交易的 LockTime 不能小于合约的锁定时间，而且输入的序号不能是最大值
+// 使用相对时间锁时，交易版本至少是2，而且输入的序号需要满足合约的相对时间锁，把 VinType.RelativeLock 设置成合约的就行
+func SignHtlcRefund(signParam *SignParam, idx int, contract *HtlcContract, privKey *btcec.PrivateKey) error {
+	if !bytes.Equal(privKey.PubKey().SerializeCompressed(), contract.RefundPubKey) {
+		return errors.New("wrong htlc private-key not match refund-pub-key")
+	}
+	if idx < 0 || idx >= len(signParam.MsgTx.TxIn) {
+		return errors.WithStack(&InputIndexError{Index: idx, Count: len(signParam.MsgTx.TxIn)})
+	}
+	msgTx := signParam.MsgTx
+	sequence := msgTx.TxIn[idx].Sequence
+	if contract.RelativeLock != nil {
+		if msgTx.Version < 2 {
+			return errors.Errorf("wrong tx version=%d, relative-lock need version>=2", msgTx.Version)
+		}
+		lock := contract.RelativeLock
+		if sequence&wire.SequenceLockTimeDisabled != 0 ||
+			(sequence&wire.SequenceLockTimeIsSeconds != 0) != lock.IsSeconds ||
+			uint16(sequence&wire.SequenceLockTimeMask) < lock.Value {
+			return errors.Errorf("wrong input sequence=%d not satisfy relative-lock sequence=%d", sequence, lock.GetSequence())
+		}
+	} else {
+		if sequence == wire.MaxTxInSequenceNum {
+			return errors.New("wrong input sequence is final, lock-time not enforced")
+		}
+		if (msgTx.LockTime < txscript.LockTimeThreshold) != (contract.AbsoluteLockTime < txscript.LockTimeThreshold) ||
+			msgTx.LockTime < contract.AbsoluteLockTime {
+			return errors.Errorf("wrong tx lock-time=%d not satisfy htlc lock-time=%d", msgTx.LockTime, contract.AbsoluteLockTime)
+		}
+	}
+	return signHtlcInput(signParam, idx, contract, privKey, [][]byte{nil})
+}
+
+// signHtlcInput 签名花费合约的输入，witness 是签名之后、脚本之前的那些数据，用于选择领取或者退款的分支
+func signHtlcInput(signParam *SignParam, idx int, contract *HtlcContract, privKey *btcec.PrivateKey, branch [][]byte) error {
+	var msgTx = signParam.MsgTx // 这里是指针传递，因此这个既是参数也是返回值
+	if idx < 0 || idx >= len(msgTx.TxIn) || idx >= len(signParam.InputOuts) {
+		return errors.WithStack(&InputIndexError{Index: idx, Count: min(len(msgTx.TxIn), len(signParam.InputOuts))})
+	}
+	script, err := contract.Script()
+	if err != nil {
+		return errors.WithMessage(err, "wrong htlc script")
+	}
+
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(newPrevOutsMap(signParam))
+	sigHashes := txscript.NewTxSigHashes(msgTx, prevOutFetcher)
+
+	inputOut := signParam.InputOuts[idx]
+	switch {
+	case txscript.IsPayToWitnessScriptHash(inputOut.PkScript):
+		sig, err := txscript.RawTxInWitnessSignature(msgTx, sigHashes, idx, inputOut.Value, script, txscript.SigHashAll, privKey)
+		if err != nil {
+			return errors.WithMessagef(err, "wrong witness-signature. index=%d", idx)
+		}
+		witness := wire.TxWitness{sig}
+		witness = append(witness, branch...)
+		msgTx.TxIn[idx].Witness = append(witness, script)
+	case txscript.IsPayToScriptHash(inputOut.PkScript):
+		sig, err := txscript.RawTxInSignature(msgTx, idx, script, txscript.SigHashAll, privKey)
+		if err != nil {
+			return errors.WithMessagef(err, "wrong signature. index=%d", idx)
+		}
+		builder := txscript.NewScriptBuilder().AddData(sig)
+		for _, data := range branch {
+			builder.AddData(data)
+		}
+		signatureScript, err := builder.AddData(script).Script()
+		if err != nil {
+			return errors.WithMessagef(err, "wrong signature-script. index=%d", idx)
+		}
+		msgTx.TxIn[idx].SignatureScript = signatureScript
+	default:
+		return errors.Errorf("wrong input index=%d pk-script is not p2wsh or p2sh", idx)
+	}
+	return verifyInputSign(msgTx, idx, inputOut, prevOutFetcher, sigHashes)
+}
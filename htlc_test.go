@@ -0,0 +1,151 @@
+package gobtcsign
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func caseNewHtlcKeys(t *testing.T) (*btcec.PrivateKey, *btcec.PrivateKey) {
+	receiverKeyBytes, err := hex.DecodeString("54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092")
+	require.NoError(t, err)
+	refundKeyBytes, err := hex.DecodeString("5f397bc72377b75db7b008a9c3fcd71651bfb138d6fc2458bb0279b9cfc8442a")
+	require.NoError(t, err)
+	receiverKey, _ := btcec.PrivKeyFromBytes(receiverKeyBytes)
+	refundKey, _ := btcec.PrivKeyFromBytes(refundKeyBytes)
+	return receiverKey, refundKey
+}
+
+func caseNewHtlcSpendParam(htlcAddress string, targetAddress string, amount int64) *BitcoinTxParams {
+	return &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 0),
+				Sender:   *NewAddressTuple(htlcAddress),
+				Amount:   amount,
+				RBFInfo:  *NewRBFActive(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple(targetAddress),
+				Amount: amount - amount/100,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+}
+
+func caseVerifyHtlcSign(t *testing.T, param *BitcoinTxParams, signParam *SignParam) {
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(newPrevOutsMap(signParam))
+	sigHashes := txscript.NewTxSigHashes(signParam.MsgTx, prevOutFetcher)
+	require.NoError(t, VerifySign(signParam.MsgTx, signParam.InputOuts, prevOutFetcher, sigHashes))
+	require.NoError(t, param.VerifyMsgTxSign(signParam.MsgTx, signParam.NetParams))
+}
+
+func TestHtlcContract_P2WSH(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	receiverKey, refundKey := caseNewHtlcKeys(t)
+	preimage := []byte("0123456789abcdef0123456789abcdef")
+
+	contract := &HtlcContract{
+		PaymentHash:      NewHtlcPaymentHash(preimage),
+		ReceiverPubKey:   receiverKey.PubKey().SerializeCompressed(),
+		RefundPubKey:     refundKey.PubKey().SerializeCompressed(),
+		AbsoluteLockTime: 3000000,
+	}
+	address, err := contract.P2WSHAddress(&netParams)
+	require.NoError(t, err)
+	t.Log("htlc-address:", address.EncodeAddress())
+
+	// 接收方凭原像领取
+	param := caseNewHtlcSpendParam(address.EncodeAddress(), "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap", 100000)
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Error(t, SignHtlcClaim(signParam, 0, contract, receiverKey, []byte("wrong-preimage")))
+	require.Error(t, SignHtlcClaim(signParam, 0, contract, refundKey, preimage))
+	require.NoError(t, SignHtlcClaim(signParam, 0, contract, receiverKey, preimage))
+	caseVerifyHtlcSign(t, param, signParam)
+
+	// 退款方在锁定时间到期后取回
+	param = caseNewHtlcSpendParam(address.EncodeAddress(), "tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx", 100000)
+	param.LockTime = 2999999
+	signParam, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Error(t, SignHtlcRefund(signParam, 0, contract, refundKey)) //还没到期
+
+	param.LockTime = 3000000
+	signParam, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Error(t, SignHtlcRefund(signParam, 0, contract, receiverKey))
+	require.NoError(t, SignHtlcRefund(signParam, 0, contract, refundKey))
+	caseVerifyHtlcSign(t, param, signParam)
+}
+
+func TestHtlcContract_P2SH_RelativeLock(t *testing.T) {
+	netParams := dogecoin.TestNetParams
+
+	receiverKey, refundKey := caseNewHtlcKeys(t)
+	preimage := []byte("fedcba9876543210fedcba9876543210")
+
+	contract := &HtlcContract{
+		PaymentHash:    NewHtlcPaymentHash(preimage),
+		ReceiverPubKey: receiverKey.PubKey().SerializeCompressed(),
+		RefundPubKey:   refundKey.PubKey().SerializeCompressed(),
+		RelativeLock:   NewRelativeLockBlocks(144),
+	}
+	_, err := contract.P2WSHAddress(&netParams)
+	require.Error(t, err) //狗狗币不支持隔离见证
+
+	address, err := contract.P2SHAddress(&netParams)
+	require.NoError(t, err)
+	t.Log("htlc-address:", address.EncodeAddress())
+
+	// 接收方凭原像领取
+	param := caseNewHtlcSpendParam(address.EncodeAddress(), "nVnVaL5e4L2GDRha9aQ7KiSXDnqjUUz1K4", 100000000)
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, SignHtlcClaim(signParam, 0, contract, receiverKey, preimage))
+	caseVerifyHtlcSign(t, param, signParam)
+
+	// 没有设置相对时间锁时不能退款
+	param = caseNewHtlcSpendParam(address.EncodeAddress(), "nkgVWbNrUowCG4mkWSzA7HHUDe3XyL2NaC", 100000000)
+	signParam, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Error(t, SignHtlcRefund(signParam, 0, contract, refundKey))
+
+	param.VinList[0].RelativeLock = contract.RelativeLock
+	signParam, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, SignHtlcRefund(signParam, 0, contract, refundKey))
+	caseVerifyHtlcSign(t, param, signParam)
+}
+
+func TestHtlcContract_Script(t *testing.T) {
+	receiverKey, refundKey := caseNewHtlcKeys(t)
+
+	contract := &HtlcContract{
+		PaymentHash:    NewHtlcPaymentHash([]byte("0123456789abcdef0123456789abcdef")),
+		ReceiverPubKey: receiverKey.PubKey().SerializeCompressed(),
+		RefundPubKey:   refundKey.PubKey().SerializeCompressed(),
+	}
+	_, err := contract.Script()
+	require.Error(t, err) //没有设置锁定时间
+
+	contract.AbsoluteLockTime = 3000000
+	script, err := contract.Script()
+	require.NoError(t, err)
+	disasm, err := txscript.DisasmString(script)
+	require.NoError(t, err)
+	t.Log(disasm)
+
+	contract.RelativeLock = NewRelativeLockBlocks(144)
+	_, err = contract.Script()
+	require.Error(t, err) //两种锁定时间只能选一个
+}
@@ -25,6 +25,9 @@ func (D *DustFee) CountDustOutput(outputs []*wire.TxOut) int64 {
 
 	var count int64
 	for _, out := range outputs {
+		if IsNullDataOutput(out) { //OP_RETURN 输出是不可花费的，不算灰尘
+			continue
+		}
 		if out.Value < minLimit {
 			count++
 		}
@@ -2,6 +2,7 @@ package dusts
 
 import (
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 )
 
@@ -14,5 +15,13 @@ func NewDustLimit(check func(output *wire.TxOut, relayFeePerKb btcutil.Amount) b
 }
 
 func (D *DustLimit) IsDustOutput(output *wire.TxOut, relayFeePerKb btcutil.Amount) bool {
+	if IsNullDataOutput(output) { //OP_RETURN 输出是携带数据的，数量通常是0，不能按灰尘拒绝
+		return false
+	}
 	return D.check(output, relayFeePerKb)
 }
+
+// IsNullDataOutput 是不是 OP_RETURN 携带数据的输出
+func IsNullDataOutput(output *wire.TxOut) bool {
+	return txscript.GetScriptClass(output.PkScript) == txscript.NullDataTy
+}
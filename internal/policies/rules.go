@@ -0,0 +1,24 @@
+package policies
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/yyle88/gobtcsign/internal/dusts"
+)
+
+// Rules 节点转发交易时的标准规则（policy），和共识规则不同，不满足时交易不会被转发，但是放进区块里是合法的
+type Rules struct {
+	MinTxVersion          int32            //最小的交易版本
+	MaxTxVersion          int32            //最大的交易版本，比特币是3（TRUC），狗狗币是2
+	MaxTxWeight           int              //交易的最大重量，即 v-size 的4倍
+	MaxSigOpsCost         int              //交易的最大签名操作数（按重量计算，传统脚本里的每个签名操作算4）
+	MaxSigScriptSize      int              //单个输入的解锁脚本的最大字节数
+	MaxDataCarrierSize    int              //OP_RETURN 输出携带数据的最大字节数，不包括 OP_RETURN 和推送数据的操作码
+	MaxDataCarrierOutputs int              //OP_RETURN 输出的最大个数
+	AllowWitness          bool             //是否支持隔离见证，狗狗币不支持
+	AllowTaproot          bool             //是否支持 taproot 输出
+	AllowBareMultiSig     bool             //是否允许裸多签输出
+	MinRelayFeePerKb      btcutil.Amount   //最低中继费率，单位是 聪/千字节，同时也是判定灰尘时使用的费率
+	DustLimit             *dusts.DustLimit //灰尘判定规则
+	DustFee               dusts.DustFee    //软灰尘的额外费用，狗狗币的节点会要求这部分手续费
+	RequireRBF            bool             //是否要求交易按 BIP125 声明可替换，TRUC 交易总是可替换的
+}
@@ -0,0 +1,83 @@
+package gobtcsign
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// NewLockTimeByHeight 按区块高度锁定，交易只能进入高度大于它的区块
+func NewLockTimeByHeight(height uint32) (uint32, error) {
+	if height >= txscript.LockTimeThreshold {
+		return 0, errors.Errorf("wrong lock-time height=%d >= threshold=%d", height, uint32(txscript.LockTimeThreshold))
+	}
+	return height, nil
+}
+
+// NewLockTimeByTime 按时间锁定，交易只能进入 MTP（前11个区块时间戳的中位数）大于它的区块
+func NewLockTimeByTime(unlockTime time.Time) (uint32, error) {
+	unix := unlockTime.Unix()
+	if unix < txscript.LockTimeThreshold || unix > int64(^uint32(0)) {
+		return 0, errors.Errorf("wrong lock-time unix=%d out of range", unix)
+	}
+	return uint32(unix), nil
+}
+
+// NewAntiFeeSnipingLockTime 防止手续费狙击的锁定时间，仿照 Bitcoin Core 钱包的逻辑
+// 把锁定时间设置为当前最新区块的高度，这样矿工就没法把交易放进重组的旧区块里抢手续费
+// 另外有 10% 的概率再往回退 0~99 个区块，让延迟广播的交易（比如走匿名网络的）和普通交易看起来没区别
+// 注意调用方需要保证 tipHeight 是最新的，当节点还在同步时不应该使用
+// 详见 https://github.com/bitcoin/bitcoin/blob/master/src/wallet/spend.cpp 的 DiscourageFeeSniping
+func NewAntiFeeSnipingLockTime(tipHeight uint32, rnd *rand.Rand) uint32 {
+	rnd = newSelectRand(rnd)
+	lockTime := tipHeight
+	if rnd.Intn(10) == 0 {
+		lockTime -= min(lockTime, uint32(rnd.Intn(100)))
+	}
+	return lockTime
+}
+
+// SetAntiFeeSniping 设置防止手续费狙击的锁定时间，详见 NewAntiFeeSnipingLockTime
+// 锁定时间只有在有输入的序号不是 wire.MaxTxInSequenceNum 时才生效，因此需要启用RBF，或者给输入设置序号
+func (param *BitcoinTxParams) SetAntiFeeSniping(tipHeight uint32, rnd *rand.Rand) {
+	param.LockTime = NewAntiFeeSnipingLockTime(tipHeight, rnd)
+}
+
+// IsLockTimeByHeight 锁定时间是不是按区块高度的
+func (param *BitcoinTxParams) IsLockTimeByHeight() bool {
+	return param.LockTime < txscript.LockTimeThreshold
+}
+
+// checkLockTimeEnforced 当设置了锁定时间时，至少要有一个输入的序号不是 wire.MaxTxInSequenceNum，否则锁定时间不生效
+func (param *BitcoinTxParams) checkLockTimeEnforced() error {
+	if param.LockTime == 0 {
+		return nil
+	}
+	for _, input := range param.VinList {
+		if param.GetTxInputSequence(input) != wire.MaxTxInSequenceNum {
+			return nil
+		}
+	}
+	return errors.Errorf("wrong lock-time=%d not enforced, all inputs have final sequence", param.LockTime)
+}
+
+// CheckLockTimeReached 检查交易在下一个区块里是否已经可以被打包
+// 按高度锁定时比较下一个区块的高度，按时间锁定时比较当前最新区块的 MTP，这是 BIP113 的规定
+func (param *BitcoinTxParams) CheckLockTimeReached(tipBlock BlockStamp) error {
+	if param.LockTime == 0 {
+		return nil
+	}
+	if param.IsLockTimeByHeight() {
+		if nextHeight := int64(tipBlock.Height) + 1; int64(param.LockTime) >= nextHeight {
+			return errors.Errorf("lock-time=%d not reached next-height=%d", param.LockTime, nextHeight)
+		}
+		return nil
+	}
+	if int64(param.LockTime) >= tipBlock.MedianTime {
+		return errors.Errorf("lock-time=%d not reached tip-median-time=%d", param.LockTime, tipBlock.MedianTime)
+	}
+	return nil
+}
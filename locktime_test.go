@@ -0,0 +1,104 @@
+package gobtcsign
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLockTimeByHeight(t *testing.T) {
+	lockTime, err := NewLockTimeByHeight(2500000)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2500000), lockTime)
+
+	_, err = NewLockTimeByHeight(500000000)
+	require.Error(t, err)
+}
+
+func TestNewLockTimeByTime(t *testing.T) {
+	lockTime, err := NewLockTimeByTime(time.Unix(1700000000, 0))
+	require.NoError(t, err)
+	require.Equal(t, uint32(1700000000), lockTime)
+
+	_, err = NewLockTimeByTime(time.Unix(100000, 0))
+	require.Error(t, err)
+}
+
+func TestNewAntiFeeSnipingLockTime(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	var backOff int
+	for idx := 0; idx < 1000; idx++ {
+		lockTime := NewAntiFeeSnipingLockTime(2500000, rnd)
+		require.LessOrEqual(t, lockTime, uint32(2500000))
+		require.GreaterOrEqual(t, lockTime, uint32(2500000-99))
+		if lockTime != 2500000 {
+			backOff++
+		}
+	}
+	require.Greater(t, backOff, 0)
+	require.Less(t, backOff, 200) //大约是 10% 的概率
+	t.Log("back-off:", backOff)
+
+	require.LessOrEqual(t, NewAntiFeeSnipingLockTime(5, rnd), uint32(5)) //不会小于0
+}
+
+func TestBitcoinTxParams_LockTime(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4900,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 4700,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+	param.SetAntiFeeSniping(3000000, rand.New(rand.NewSource(1)))
+	require.True(t, param.IsLockTimeByHeight())
+
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Equal(t, param.LockTime, signParam.MsgTx.LockTime)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, param.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	// 反拼出的参数里也有锁定时间
+	preMap := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		*MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0): NewSenderAmountUtxo(NewAddressTuple(senderAddress), 4900),
+	})
+	customParam, err := NewCustomParamFromMsgTx(signParam.MsgTx, preMap)
+	require.NoError(t, err)
+	require.Equal(t, param.LockTime, customParam.LockTime)
+	require.NoError(t, customParam.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.LockTime++
+	require.ErrorIs(t, param.CheckMsgTxParam(msgTx, &netParams), ErrMsgTxMismatch)
+
+	require.Error(t, param.CheckLockTimeReached(BlockStamp{Height: int32(param.LockTime) - 1}))
+	require.NoError(t, param.CheckLockTimeReached(BlockStamp{Height: int32(param.LockTime)}))
+
+	// 全部输入的序号都是最大值时，锁定时间不生效
+	param.RBFInfo = *NewRBFNotUse()
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err)
+	t.Log(err)
+}
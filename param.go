@@ -1,34 +1,103 @@
 package gobtcsign
 
 import (
+	"context"
+
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/pkg/errors"
 )
 
 // BitcoinTxParams 这是客户自定义的参数类型，表示要转入和转出的信息
 type BitcoinTxParams struct {
-	VinList []VinType //要转入进BTC节点的
-	OutList []OutType //要从BTC节点转出的-这里面通常包含1个目标（转账）和1个自己（找零）
-	RBFInfo RBFConfig //详见RBF机制，通常是需要启用RBF以免交易长期被卡的
+	VinList  []VinType  //要转入进BTC节点的
+	OutList  []OutType  //要从BTC节点转出的-这里面通常包含1个目标（转账）和1个自己（找零）
+	RBFInfo  RBFConfig  //详见RBF机制，通常是需要启用RBF以免交易长期被卡的
+	Ordering TxOrdering //输入和输出的排列方式，默认保持顺序，推荐使用 BIP69 或随机排列，以免暴露哪个输出是找零
+	LockTime uint32     //交易的锁定时间，为0时不锁定，小于 txscript.LockTimeThreshold 时是区块高度，否则是时间戳，详见 NewAntiFeeSnipingLockTime
+	Version  int32      //交易版本，可选1、2、3，为0时自动选择，详见 GetTxVersion，版本3是 BIP431 的 TRUC 交易，详见 TrucTxVersion
+	FeeGuard *FeeGuard  //签名前的安全检查，为空时使用默认的 NewFeeGuard()，详见 FeeGuard
 }
 
 type VinType struct {
-	OutPoint wire.OutPoint //UTXO的主要信息
-	Sender   AddressTuple  //发送者信息，钱包地址或者公钥文本，二选一填写即可
-	Amount   int64         //发送数量，因为这里不是浮点数，因此很明显这里传的是聪的数量
-	RBFInfo  RBFConfig     //还是RBF机制，前面的是控制整个交易的，这里控制单个UTXO的
+	OutPoint     wire.OutPoint //UTXO的主要信息
+	Sender       AddressTuple  //发送者信息，钱包地址或者公钥文本，二选一填写即可
+	Amount       int64         //发送数量，因为这里不是浮点数，因此很明显这里传的是聪的数量
+	RBFInfo      RBFConfig     //还是RBF机制，前面的是控制整个交易的，这里控制单个UTXO的
+	Unconfirmed  bool          //UTXO是否还没有被确认，RBF的替换交易不能新增未确认的输入
+	RelativeLock *RelativeLock //BIP68 相对时间锁，为空时不启用，设置后由它决定输入的序号，而且交易版本会自动变为2
 }
 
 type OutType struct {
-	Target AddressTuple //接收者信息，钱包地址和公钥文本，二选一填写即可
-	Amount int64        //聪的数量
+	Target   AddressTuple //接收者信息，钱包地址和公钥文本，二选一填写即可
+	Amount   int64        //聪的数量
+	NullData []byte       //OP_RETURN 携带的数据，比如充值标签或者存证哈希，设置时 Target 留空，Amount 通常是0，使用 NewNullDataOutput 创建
+	IsChange bool         //是否是找零输出，ChangeBuilder 会设置它，配置 FeeGuard.ChangeTargets 时会检查找零地址
+}
+
+// NewNullDataOutput 创建 OP_RETURN 携带数据的输出，常用于交易所的充值标签和存证
+// 数据不能超过 txscript.MaxDataCarrierSize 即80字节，否则节点不会转发，而且每个交易只能有一个这样的输出
+// 这个输出的数量是0，它不可花费，也不会被当作灰尘，数据为空时得到 OP_RETURN OP_0 即 6a00 的输出
+func NewNullDataOutput(data []byte) (OutType, error) {
+	if len(data) > txscript.MaxDataCarrierSize {
+		return OutType{}, errors.WithMessagef(ErrNonStandard, "wrong null-data size=%d > max=%d", len(data), txscript.MaxDataCarrierSize)
+	}
+	if data == nil {
+		data = []byte{} //IsNullData 通过是否为 nil 判断，因此空数据也不能是 nil
+	}
+	return OutType{NullData: data, Amount: 0}, nil
+}
+
+// IsNullData 是不是 OP_RETURN 携带数据的输出，NullData 是空切片（不是 nil）时也是，因此需要使用 NewNullDataOutput 创建
+func (out *OutType) IsNullData() bool {
+	return out.NullData != nil
+}
+
+// GetPkScript 获得输出的脚本，携带数据时是 OP_RETURN <data>，否则是接收者地址的脚本
+func (out *OutType) GetPkScript(netParams *chaincfg.Params) ([]byte, error) {
+	if out.IsNullData() {
+		if out.Target.Address != "" || out.Target.PkScript != nil {
+			return nil, errors.WithMessage(ErrAddressPkScriptMismatch, "wrong null-data output with target")
+		}
+		pkScript, err := txscript.NullDataScript(out.NullData)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong null-data")
+		}
+		return pkScript, nil
+	}
+	return out.Target.GetPkScript(netParams)
+}
+
+// Clone 复制交易参数，输入和输出列表也是新的，修改副本的列表不会影响原来的参数
+func (param *BitcoinTxParams) Clone() *BitcoinTxParams {
+	cp := *param
+	cp.VinList = append([]VinType{}, param.VinList...)
+	cp.OutList = append([]OutType{}, param.OutList...)
+	return &cp
 }
 
 // CreateTxSignParams 根据用户的输入信息拼接交易
 func (param *BitcoinTxParams) CreateTxSignParams(netParams *chaincfg.Params) (*SignParam, error) {
-	var msgTx = wire.NewMsgTx(wire.TxVersion)
+	//设置了锁定时间时，需要有输入的序号不是最大值，否则锁定时间不生效
+	if err := param.checkLockTimeEnforced(); err != nil {
+		return nil, err
+	}
+	//检查交易版本，版本3时还要检查 TRUC 的拓扑和大小限制
+	if err := param.checkTxVersion(netParams); err != nil {
+		return nil, err
+	}
+	//检查P2A输出，灰尘数量的P2A输出只能出现在 TRUC 交易里
+	if err := param.checkAnchorOutputs(netParams); err != nil {
+		return nil, err
+	}
+	//签名前的安全检查，避免手续费是负数或者过高，以及找零转到未知的地址
+	if err := param.CheckFeeGuard(netParams); err != nil {
+		return nil, err
+	}
+	var msgTx = wire.NewMsgTx(param.GetTxVersion())
+	msgTx.LockTime = param.LockTime
 
 	//这是发送者和发送数量的列表，很明显，这是需要签名的关键信息，现在只把待签名信息收集起来
 	var inputOuts = make([]*wire.TxOut, 0, len(param.VinList))
@@ -55,24 +124,39 @@ func (param *BitcoinTxParams) CreateTxSignParams(netParams *chaincfg.Params) (*S
 	}
 
 	//设置 vout 列表，这个不需要签名，因此只要把目标地址和数量设置上就行
+	var nullDataCount = 0
 	for _, output := range param.OutList {
-		pkScript, err := output.Target.GetPkScript(netParams)
+		pkScript, err := output.GetPkScript(netParams)
 		if err != nil {
 			return nil, errors.WithMessage(err, "wrong target.address->pk-script")
 		}
+		//按脚本类型判断，直接在 Target.PkScript 里填写的 OP_RETURN 脚本也要计数
+		if txscript.GetScriptClass(pkScript) == txscript.NullDataTy {
+			if nullDataCount++; nullDataCount > 1 { //节点的标准规则只允许一个 OP_RETURN 输出
+				return nil, errors.WithMessage(ErrNonStandard, "wrong null-data output count > 1")
+			}
+		}
 		msgTx.AddTxOut(wire.NewTxOut(output.Amount, pkScript))
 	}
+
+	//按配置重新排列输入和输出，待签名信息会跟着输入一起排列
+	txOutIndexes, err := param.Ordering.apply(msgTx, inputOuts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong tx-ordering")
+	}
 	return &SignParam{
-		MsgTx:     msgTx,
-		InputOuts: inputOuts, //这里它和 vin 的数量完全相同，而且位置序号也相同，最终签名时也需要确保位置相同
-		NetParams: netParams,
+		MsgTx:        msgTx,
+		InputOuts:    inputOuts, //这里它和 vin 的数量完全相同，而且位置序号也相同，最终签名时也需要确保位置相同
+		NetParams:    netParams,
+		FeeGuard:     param.FeeGuard,
+		TxOutIndexes: txOutIndexes,
 	}, nil
 }
 
 func (param *BitcoinTxParams) GetOutputs(netParams *chaincfg.Params) ([]*wire.TxOut, error) {
 	outputs := make([]*wire.TxOut, 0, len(param.OutList))
 	for _, output := range param.OutList {
-		pkScript, err := output.Target.GetPkScript(netParams)
+		pkScript, err := output.GetPkScript(netParams)
 		if err != nil {
 			return nil, errors.WithMessage(err, "wrong target.address->pk-script")
 		}
@@ -82,6 +166,10 @@ func (param *BitcoinTxParams) GetOutputs(netParams *chaincfg.Params) ([]*wire.Tx
 }
 
 func (param *BitcoinTxParams) GetTxInputSequence(input VinType) uint32 {
+	// 相对时间锁和RBF共用序号，设置了相对时间锁时以它为准，这时的序号也表示可替换
+	if input.RelativeLock != nil {
+		return input.RelativeLock.GetSequence()
+	}
 	// 当你确实是需要对每个交易单独设置RBF时，就可以在这里设置，单独设置到这个 vin 里面
 	if seqNo := input.RBFInfo.GetSequence(); seqNo != wire.MaxTxInSequenceNum { //启用RBF机制，精确的RBF逻辑
 		return seqNo
@@ -149,12 +237,37 @@ func (param *BitcoinTxParams) EstimateTxFee(netParams *chaincfg.Params, change *
 // 第二个参数是设置如何获取前置输出的
 // 通常是使用 客户端 请求获取前置输出，但也可以使用map把前置输出存起来，因此使用 interface 获取前置输出，提供两种实现方案
 // 在项目中推荐使用 rpc 获取，这样就很方便，而在单元测试中则只需要通过 map 预先配置就行，避免网络请求也避免暴露节点配置
+// 反拼出的输出只有公钥脚本，需要展示地址时再调用 ResolveAddresses 反推出地址
 func NewCustomParamFromMsgTx(msgTx *wire.MsgTx, preImp GetUtxoFromInterface) (*BitcoinTxParams, error) {
+	return newCustomParamFromMsgTx(msgTx, preImp.GetUtxoFrom)
+}
+
+// NewCustomParamFromMsgTxWithContext 和 NewCustomParamFromMsgTx 相同，但是批量并发地获取前置输出，而且可以通过 ctx 取消
+// 输入很多时（比如归集交易）逐个请求节点会很慢，这时使用这个函数
+func NewCustomParamFromMsgTxWithContext(ctx context.Context, msgTx *wire.MsgTx, batchImp GetUtxoFromBatchInterface) (*BitcoinTxParams, error) {
+	var utxos = make([]wire.OutPoint, 0, len(msgTx.TxIn))
+	for _, vin := range msgTx.TxIn {
+		utxos = append(utxos, vin.PreviousOutPoint)
+	}
+	utxoMap, err := batchImp.GetUtxosFrom(ctx, utxos)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get-utxos-from")
+	}
+	return newCustomParamFromMsgTx(msgTx, func(utxo wire.OutPoint) (*SenderAmountUtxo, error) {
+		utxoFrom, ok := utxoMap[utxo]
+		if !ok {
+			return nil, errors.WithMessagef(ErrInputOutMissing, "wrong utxo[%s:%d] not-exist-in-batch-result", utxo.Hash.String(), utxo.Index)
+		}
+		return utxoFrom, nil
+	})
+}
+
+func newCustomParamFromMsgTx(msgTx *wire.MsgTx, getUtxoFrom func(utxo wire.OutPoint) (*SenderAmountUtxo, error)) (*BitcoinTxParams, error) {
 	var vinList = make([]VinType, 0, len(msgTx.TxIn))
 	for _, vin := range msgTx.TxIn {
 		costUtxo := vin.PreviousOutPoint
 
-		utxoFrom, err := preImp.GetUtxoFrom(costUtxo)
+		utxoFrom, err := getUtxoFrom(costUtxo)
 		if err != nil {
 			return nil, errors.WithMessage(err, "get-utxo-from")
 		}
@@ -176,16 +289,42 @@ func NewCustomParamFromMsgTx(msgTx *wire.MsgTx, preImp GetUtxoFromInterface) (*B
 	}
 
 	param := &BitcoinTxParams{
-		VinList: vinList,
-		OutList: outList,
-		RBFInfo: *NewRBFNotUse(), //这里是不需要的，因为各个输入里将会有RBF的全部信息
+		VinList:  vinList,
+		OutList:  outList,
+		RBFInfo:  *NewRBFNotUse(), //这里是不需要的，因为各个输入里将会有RBF的全部信息
+		LockTime: msgTx.LockTime,
+		Version:  msgTx.Version,
 	}
 	return param, nil
 }
 
+// ResolveAddresses 把只有公钥脚本的输入和输出反推出钱包地址，通常在 NewCustomParamFromMsgTx 以后调用，以便展示真实的目标地址
+// 没有对应地址的脚本（比如 OP_RETURN 和非标准脚本）保持只有公钥脚本，不算错误
+func (param *BitcoinTxParams) ResolveAddresses(netParams *chaincfg.Params) error {
+	for idx := range param.VinList {
+		if _, err := param.VinList[idx].Sender.Resolve(netParams); err != nil {
+			return errors.WithMessagef(err, "wrong resolve vin[%d] sender", idx)
+		}
+	}
+	for idx := range param.OutList {
+		if param.OutList[idx].IsNullData() {
+			continue
+		}
+		if _, err := param.OutList[idx].Target.Resolve(netParams); err != nil {
+			return errors.WithMessagef(err, "wrong resolve out[%d] target", idx)
+		}
+	}
+	return nil
+}
+
 // VerifyMsgTxSign 使用这个检查签名是否正确
 func (param *BitcoinTxParams) VerifyMsgTxSign(msgTx *wire.MsgTx, netParams *chaincfg.Params) error {
-	inputsItem, err := param.GetVerifyTxInputsItem(netParams)
+	//交易里的输入可能被重新排列过，因此按 OutPoint 找到每个输入对应的参数
+	vinList, err := param.alignVinList(msgTx)
+	if err != nil {
+		return errors.WithMessage(err, "wrong align-vin-list")
+	}
+	inputsItem, err := (&BitcoinTxParams{VinList: vinList}).GetVerifyTxInputsItem(netParams)
 	if err != nil {
 		return errors.WithMessage(err, "wrong get-inputs")
 	}
@@ -1,10 +1,12 @@
 package gobtcsign
 
 import (
+	"encoding/hex"
 	"testing"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/stretchr/testify/require"
 	"github.com/yyle88/gobtcsign/dogecoin"
@@ -126,6 +128,21 @@ func TestCustomParam_CheckMsgTxParam(t *testing.T) {
 	t.Log("success")
 	require.NoError(t, param.CheckMsgTxParam(msgTx, &netParams))
 	t.Log("success")
+
+	//输出的数量不同
+	mismatchTx := msgTx.Copy()
+	mismatchTx.TxOut[0].Value++
+	require.ErrorIs(t, param.CheckMsgTxParam(mismatchTx, &netParams), ErrMsgTxMismatch)
+
+	//输出的脚本不同
+	mismatchTx = msgTx.Copy()
+	mismatchTx.TxOut[0].PkScript = mismatchTx.TxOut[1].PkScript
+	require.ErrorIs(t, param.CheckMsgTxParam(mismatchTx, &netParams), ErrAddressPkScriptMismatch)
+
+	//输入的位置不同
+	mismatchTx = msgTx.Copy()
+	mismatchTx.TxIn[0].PreviousOutPoint.Index++
+	require.ErrorIs(t, param.CheckMsgTxParam(mismatchTx, &netParams), ErrMsgTxMismatch)
 }
 
 func TestCustomParam_CheckMsgTxParam_BTC(t *testing.T) {
@@ -179,3 +196,86 @@ func TestCustomParam_CheckMsgTxParam_BTC_TXN(t *testing.T) {
 	require.NoError(t, param.CheckMsgTxParam(msgTx, &netParams))
 	t.Log("success")
 }
+
+func TestNewNullDataOutput(t *testing.T) {
+	output, err := NewNullDataOutput([]byte("hello"))
+	require.NoError(t, err)
+	require.True(t, output.IsNullData())
+	require.Equal(t, int64(0), output.Amount)
+
+	pkScript, err := output.GetPkScript(&chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, "6a0568656c6c6f", hex.EncodeToString(pkScript))
+
+	_, err = NewNullDataOutput(make([]byte, txscript.MaxDataCarrierSize+1))
+	require.Error(t, err)
+
+	//没有数据时也是 OP_RETURN 输出，脚本是 OP_RETURN OP_0
+	output, err = NewNullDataOutput(nil)
+	require.NoError(t, err)
+	require.True(t, output.IsNullData())
+	pkScript, err = output.GetPkScript(&chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.Equal(t, "6a00", hex.EncodeToString(pkScript))
+}
+
+func TestCustomParam_NullDataOutput(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092"
+
+	netParams := chaincfg.TestNet3Params
+
+	memo, err := NewNullDataOutput([]byte("deposit-tag:123456"))
+	require.NoError(t, err)
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 1234,
+			},
+			memo,
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	require.True(t, res.HasChange())
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.True(t, txscript.IsNullData(signParam.MsgTx.TxOut[1].PkScript))
+
+	//估算的大小里包含了 OP_RETURN 输出
+	withoutMemo := &BitcoinTxParams{VinList: param.VinList, OutList: param.OutList[:1], RBFInfo: param.RBFInfo}
+	fee, err := EstimateTxFee(withoutMemo, &netParams, change, 1000, NewDustFee())
+	require.NoError(t, err)
+	require.Greater(t, res.Fee, fee)
+
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, res.TxParams.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	//只能有一个 OP_RETURN 输出
+	twice := &BitcoinTxParams{VinList: param.VinList, OutList: []OutType{memo, memo}, RBFInfo: param.RBFInfo}
+	_, err = twice.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrNonStandard)
+
+	//直接填写 OP_RETURN 脚本的输出也要计数
+	memoScript, err := memo.GetPkScript(&netParams)
+	require.NoError(t, err)
+	rawMemo := OutType{Target: AddressTuple{PkScript: memoScript}}
+	twice = &BitcoinTxParams{VinList: param.VinList, OutList: []OutType{memo, rawMemo}, RBFInfo: param.RBFInfo}
+	_, err = twice.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrNonStandard)
+}
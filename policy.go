@@ -0,0 +1,184 @@
+package gobtcsign
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/internal/policies"
+)
+
+type PolicyRules = policies.Rules
+
+// NewPolicyRules 比特币节点的标准规则，数值和 Bitcoin Core 的默认配置相同，狗狗币使用 dogecoin.NewDogePolicyRules()
+// 具体参考链接在
+// https://github.com/bitcoin/bitcoin/blob/master/src/policy/policy.h
+func NewPolicyRules() *PolicyRules {
+	return &PolicyRules{
+		MinTxVersion:          1,
+		MaxTxVersion:          TrucTxVersion,
+		MaxTxWeight:           400000,
+		MaxSigOpsCost:         16000,
+		MaxSigScriptSize:      1650,
+		MaxDataCarrierSize:    txscript.MaxDataCarrierSize,
+		MaxDataCarrierOutputs: 1,
+		AllowWitness:          true,
+		AllowTaproot:          true,
+		AllowBareMultiSig:     true,
+		MinRelayFeePerKb:      txrules.DefaultRelayFeePerKb,
+		DustLimit:             NewDustLimit(),
+		DustFee:               NewDustFee(),
+		RequireRBF:            false,
+	}
+}
+
+// PolicyError 交易不满足节点的标准规则时返回这个错误，可以使用 errors.Is 判断 ErrNonStandard
+type PolicyError struct {
+	Reason string //和节点拒绝交易时的原因相同，比如 "dust"、"tx-size"、"scriptpubkey"、"min relay fee not met"
+	Index  int    //不满足规则的输入或者输出的位置，是整个交易的问题时是-1
+	Detail string //详细的信息
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("non-standard reason=%s index=%d: %s", e.Reason, e.Index, e.Detail)
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrNonStandard
+}
+
+func newPolicyError(reason string, index int, format string, args ...interface{}) error {
+	return errors.WithStack(&PolicyError{Reason: reason, Index: index, Detail: fmt.Sprintf(format, args...)})
+}
+
+// CheckTxPolicy 检查签名后的交易是否满足节点的标准规则，VerifySign 只检查签名，而节点还会因为这些规则拒绝转发交易
+// inputOuts 是各个输入的前置输出，和交易的输入一一对应，遇到第一个不满足的规则时返回 *PolicyError
+// 零手续费的 TRUC 交易可以带一个灰尘输出（比如P2A），这时不检查最低中继费，它需要和花费它的子交易一起作为交易包广播
+func CheckTxPolicy(msgTx *wire.MsgTx, inputOuts []*wire.TxOut, rules *PolicyRules) error {
+	if len(inputOuts) != len(msgTx.TxIn) {
+		return errors.Errorf("wrong input-outs count=%d tx-in count=%d", len(inputOuts), len(msgTx.TxIn))
+	}
+	if msgTx.Version < rules.MinTxVersion || msgTx.Version > rules.MaxTxVersion {
+		return newPolicyError("version", -1, "tx version=%d not in [%d, %d]", msgTx.Version, rules.MinTxVersion, rules.MaxTxVersion)
+	}
+	if msgTx.HasWitness() && !rules.AllowWitness {
+		return newPolicyError("no-witness-yet", -1, "tx has witness but chain not support witness")
+	}
+	tx := btcutil.NewTx(msgTx)
+	if weight := blockchain.GetTransactionWeight(tx); weight > int64(rules.MaxTxWeight) {
+		return newPolicyError("tx-size", -1, "tx weight=%d > max=%d", weight, rules.MaxTxWeight)
+	}
+	vSize := GetMsgTxVSize(msgTx)
+	if msgTx.Version == TrucTxVersion && vSize > TrucMaxVSize {
+		return newPolicyError("TRUC-violation", -1, "truc tx v-size=%d > max=%d", vSize, TrucMaxVSize)
+	}
+
+	//检查输入，解锁脚本只能推送数据，前置输出也需要是标准的脚本
+	var inputAmount int64
+	for idx, txIn := range msgTx.TxIn {
+		if size := len(txIn.SignatureScript); size > rules.MaxSigScriptSize {
+			return newPolicyError("scriptsig-size", idx, "sig-script size=%d > max=%d", size, rules.MaxSigScriptSize)
+		}
+		if !txscript.IsPushOnlyScript(txIn.SignatureScript) {
+			return newPolicyError("scriptsig-not-pushonly", idx, "sig-script is not push-only")
+		}
+		if !isStandardPkScript(inputOuts[idx].PkScript, rules) || isDataCarrierScript(inputOuts[idx].PkScript) {
+			return newPolicyError("bad-txns-nonstandard-inputs", idx, "prev-out pk-script=%x is not standard", inputOuts[idx].PkScript)
+		}
+		inputAmount += inputOuts[idx].Value
+	}
+
+	//检查输出，脚本需要是标准的，OP_RETURN 的个数和大小有限制，而且不能是灰尘
+	var outputAmount int64
+	var dustIndexes []int
+	var dataCarrierCount = 0
+	for idx, txOut := range msgTx.TxOut {
+		if !isStandardPkScript(txOut.PkScript, rules) {
+			return newPolicyError("scriptpubkey", idx, "pk-script=%x is not standard", txOut.PkScript)
+		}
+		if isDataCarrierScript(txOut.PkScript) {
+			if dataCarrierCount++; dataCarrierCount > rules.MaxDataCarrierOutputs {
+				return newPolicyError("multi-op-return", idx, "op-return output count > %d", rules.MaxDataCarrierOutputs)
+			}
+			if size := len(txOut.PkScript); size > rules.MaxDataCarrierSize+3 { //3是 OP_RETURN 和推送数据的操作码
+				return newPolicyError("scriptpubkey", idx, "op-return pk-script size=%d > max=%d", size, rules.MaxDataCarrierSize+3)
+			}
+		} else if rules.DustLimit.IsDustOutput(txOut, rules.MinRelayFeePerKb) {
+			dustIndexes = append(dustIndexes, idx)
+		}
+		outputAmount += txOut.Value
+	}
+
+	fee := btcutil.Amount(inputAmount - outputAmount)
+	if fee < 0 {
+		return newPolicyError("bad-txns-in-belowout", -1, "input=%d < output=%d", inputAmount, outputAmount)
+	}
+	//零手续费的 TRUC 交易可以带一个灰尘输出，这是临时灰尘（ephemeral dust），在同一个交易包里就会被花掉
+	isEphemeral := msgTx.Version == TrucTxVersion && fee == 0 && len(dustIndexes) == 1
+	if len(dustIndexes) > 0 && !isEphemeral {
+		return newPolicyError("dust", dustIndexes[0], "output value=%d is dust", msgTx.TxOut[dustIndexes[0]].Value)
+	}
+
+	sigOpsCost, err := blockchain.GetSigOpCost(tx, false, newPolicyUtxoView(msgTx, inputOuts), true, rules.AllowWitness)
+	if err != nil {
+		return errors.WithMessage(err, "wrong get-sig-op-cost")
+	}
+	if sigOpsCost > rules.MaxSigOpsCost {
+		return newPolicyError("bad-txns-too-many-sigops", -1, "sig-ops-cost=%d > max=%d", sigOpsCost, rules.MaxSigOpsCost)
+	}
+
+	if !isEphemeral {
+		required := txrules.FeeForSerializeSize(rules.MinRelayFeePerKb, vSize) + rules.DustFee.SumExtraDustFee(msgTx.TxOut)
+		if fee < required {
+			return newPolicyError("min relay fee not met", -1, "fee=%d < required=%d v-size=%d", fee, required, vSize)
+		}
+	}
+
+	if rules.RequireRBF && msgTx.Version != TrucTxVersion {
+		if err := CheckReplaceable(msgTx); err != nil {
+			return newPolicyError("txn-not-replaceable", -1, "%s", err.Error())
+		}
+	}
+	return nil
+}
+
+// isStandardPkScript 是不是标准的输出脚本，P2A 是未知版本的见证程序，在支持隔离见证的链上也是标准的
+func isStandardPkScript(pkScript []byte, rules *PolicyRules) bool {
+	if IsPayToAnchor(pkScript) { //btcd 不认识P2A，会把它当作不标准的脚本
+		return rules.AllowWitness
+	}
+	if isDataCarrierScript(pkScript) { //大小由 MaxDataCarrierSize 单独检查
+		return true
+	}
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyHashTy, txscript.ScriptHashTy, txscript.PubKeyTy, txscript.NullDataTy:
+		return true
+	case txscript.MultiSigTy:
+		return rules.AllowBareMultiSig
+	case txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy, txscript.WitnessUnknownTy:
+		return rules.AllowWitness
+	case txscript.WitnessV1TaprootTy:
+		return rules.AllowWitness && rules.AllowTaproot
+	default:
+		return false
+	}
+}
+
+// isDataCarrierScript 是不是 OP_RETURN 携带数据的脚本，和 Bitcoin Core 相同，只要 OP_RETURN 后面只推送数据就算
+// btcd 的 GetScriptClass 会把超过80字节的 OP_RETURN 当作不标准的脚本，这样 MaxDataCarrierSize 就不起作用了，因此这里不用它
+func isDataCarrierScript(pkScript []byte) bool {
+	return len(pkScript) > 0 && pkScript[0] == txscript.OP_RETURN && txscript.IsPushOnlyScript(pkScript[1:])
+}
+
+// newPolicyUtxoView 把前置输出放进 UtxoViewpoint 里，用于计算签名操作数
+func newPolicyUtxoView(msgTx *wire.MsgTx, inputOuts []*wire.TxOut) *blockchain.UtxoViewpoint {
+	view := blockchain.NewUtxoViewpoint()
+	for idx, txIn := range msgTx.TxIn {
+		view.Entries()[txIn.PreviousOutPoint] = blockchain.NewUtxoEntry(inputOuts[idx], 0, false)
+	}
+	return view
+}
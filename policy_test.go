@@ -0,0 +1,160 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func caseRequirePolicyReason(t *testing.T, err error, reason string) {
+	require.ErrorIs(t, err, ErrNonStandard)
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr))
+	require.Equal(t, reason, policyErr.Reason)
+	t.Log(err)
+}
+
+func TestCheckTxPolicy(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	_, signParam := caseNewVerifyReportSignParam(t, senderAddress, privateKeyHex, &netParams)
+	require.NoError(t, CheckTxPolicy(signParam.MsgTx, signParam.InputOuts, NewPolicyRules()))
+
+	//要求声明可替换时，这个交易是可以通过的
+	rules := NewPolicyRules()
+	rules.RequireRBF = true
+	require.NoError(t, CheckTxPolicy(signParam.MsgTx, signParam.InputOuts, rules))
+
+	//没有声明可替换
+	msgTx := signParam.MsgTx.Copy()
+	for _, txIn := range msgTx.TxIn {
+		txIn.Sequence = wire.MaxTxInSequenceNum
+	}
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules), "txn-not-replaceable")
+
+	//交易版本不对
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.Version = 4
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "version")
+
+	//灰尘输出
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut[0].Value -= 100
+	msgTx.TxOut = append(msgTx.TxOut, wire.NewTxOut(100, msgTx.TxOut[0].PkScript))
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "dust")
+
+	//手续费不够
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut[0].Value += 1900
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "min relay fee not met")
+
+	//不标准的输出
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut[0].PkScript = []byte{txscript.OP_TRUE}
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "scriptpubkey")
+
+	//两个 OP_RETURN 输出
+	nullData, err := txscript.NullDataScript([]byte("memo"))
+	require.NoError(t, err)
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut = append(msgTx.TxOut, wire.NewTxOut(0, nullData), wire.NewTxOut(0, nullData))
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "multi-op-return")
+
+	//OP_RETURN 的数据太长
+	builder := txscript.NewScriptBuilder(txscript.WithScriptAllocSize(100)).AddOp(txscript.OP_RETURN)
+	bigNullData, err := builder.AddFullData(make([]byte, txscript.MaxDataCarrierSize+1)).Script()
+	require.NoError(t, err)
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut = append(msgTx.TxOut, wire.NewTxOut(0, bigNullData))
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "scriptpubkey")
+
+	//放宽 OP_RETURN 的大小限制以后就可以了，就像 Bitcoin Core 的 -datacarriersize 配置
+	rules = NewPolicyRules()
+	rules.MaxDataCarrierSize = 100
+	require.NoError(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules))
+
+	//解锁脚本不是只推送数据
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxIn[0].SignatureScript = []byte{txscript.OP_DUP}
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "scriptsig-not-pushonly")
+}
+
+func TestCheckTxPolicy_EphemeralAnchor(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	signParam, err := caseNewAnchorParentParam(0, TrucTxVersion).CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+
+	//零手续费的 TRUC 交易可以带一个零数量的P2A输出
+	require.NoError(t, CheckTxPolicy(signParam.MsgTx, signParam.InputOuts, NewPolicyRules()))
+
+	//不是 TRUC 交易时就是灰尘
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.Version = 2
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, NewPolicyRules()), "dust")
+}
+
+func TestCheckTxPolicy_DOGE(t *testing.T) {
+	const senderAddress = "nkgVWbNrUowCG4mkWSzA7HHUDe3XyL2NaC"
+	const privateKeyHex = "5f397bc72377b75db7b008a9c3fcd71651bfb138d6fc2458bb0279b9cfc8442a" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := dogecoin.TestNetParams
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("57a3514865d3f4c5cbd49270204aaf4928c4c10651430dcd0cb79b80cda5ef0b", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   6799372,
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("ng4P16anXNUrQw6VKHmoMW8NHsTkFBdNrn"),
+				Amount: 6799372 - 345678,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+
+	rules := dogecoin.NewDogePolicyRules()
+	require.NoError(t, CheckTxPolicy(signParam.MsgTx, signParam.InputOuts, rules))
+
+	//狗狗币的规则里低于 0.001 DOGE 的输出是灰尘，和费率无关
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.TxOut[0].Value -= 90000
+	msgTx.TxOut = append(msgTx.TxOut, wire.NewTxOut(90000, msgTx.TxOut[0].PkScript))
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules), "dust")
+
+	//软灰尘需要额外交 0.01 DOGE 的手续费
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut[0].Value -= 500000
+	msgTx.TxOut = append(msgTx.TxOut, wire.NewTxOut(500000, msgTx.TxOut[0].PkScript))
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules), "min relay fee not met")
+	msgTx.TxOut[0].Value -= dogecoin.ExtraDustsFee
+	require.NoError(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules))
+
+	//狗狗币没有版本3，也没有隔离见证
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.Version = TrucTxVersion
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules), "version")
+
+	msgTx = signParam.MsgTx.Copy()
+	msgTx.TxOut[0].PkScript = MustGetPkScript(MustNewAddress("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx", &chaincfg.TestNet3Params))
+	caseRequirePolicyReason(t, CheckTxPolicy(msgTx, signParam.InputOuts, rules), "scriptpubkey")
+}
@@ -0,0 +1,170 @@
+package gobtcsign
+
+import (
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// DefaultIncrementalRelayFeePerKb 节点默认的增量中继费率，单位是 聪/千字节，替换交易多交的手续费至少要能支付自身大小的这个费率
+const DefaultIncrementalRelayFeePerKb = 1000
+
+// BumpFeeParam 提高手续费的参数，用于替换卡在内存池里的交易
+// 原交易可以通过 NewMsgTxFromHex 和 NewCustomParamFromMsgTx 得到
+type BumpFeeParam struct {
+	OrigTx                   *wire.MsgTx      //原交易，已经签名的
+	OrigParams               *BitcoinTxParams //原交易的参数，需要包含每个输入的数量，以计算原交易的手续费，没有设置 Ordering 时输出的顺序需要和原交易相同
+	ChangeIndex              int              //原交易的找零输出在 OrigParams.OutList 里的位置，新的手续费从这里扣除，为-1时表示原交易没有找零
+	ChangeTo                 *ChangeTo        //当原交易没有找零，而且需要追加输入时使用的找零信息，为空时多出来的都作为手续费
+	FeeRatePerKb             btcutil.Amount   //新的费率，单位是 聪/千字节，需要高于原交易的费率
+	IncrementalRelayFeePerKb btcutil.Amount   //增量中继费率，为0时使用默认的 DefaultIncrementalRelayFeePerKb
+	DustFee                  DustFee          //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit                *DustLimit       //灰尘判定规则，为空时使用比特币的规则，找零减少到灰尘时就去掉找零
+	RelayFeePerKb            btcutil.Amount   //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	ExtraVinList             []VinType        //找零不够扣时可以追加的输入，按数量从大到小追加，必须是已确认的UTXO
+}
+
+// BumpFeeResult 提高手续费的结果
+type BumpFeeResult struct {
+	TxParams     *BitcoinTxParams //替换交易的参数，需要重新签名，排列方式沿用 OrigParams.Ordering
+	// 找零保留下来时，原交易的输出在 OutList 里保持原来的位置
+	// 找零变成灰尘被去掉时，排在找零后面的输出都会前移一位，这时需要按原来的位置对账的调用方要自己调整
+	Fee          btcutil.Amount   //替换交易的手续费
+	OrigFee      btcutil.Amount   //原交易的手续费
+	ChangeAmount btcutil.Amount   //找零的数量，为0时表示没有找零输出
+	ChangeIndex  int              //找零输出在 OutList 里的位置，为-1时表示没有找零输出，设置了 Ordering 时使用 SignParam.GetTxOutIndex 得到在交易里的位置
+}
+
+// GetIncrementalRelayFeePerKb 获得增量中继费率
+func (param *BumpFeeParam) GetIncrementalRelayFeePerKb() btcutil.Amount {
+	if param.IncrementalRelayFeePerKb > 0 {
+		return param.IncrementalRelayFeePerKb
+	}
+	return DefaultIncrementalRelayFeePerKb
+}
+
+// BumpFee 拼出提高手续费的替换交易，满足 BIP125 的规则
+// 1. 新手续费不低于原手续费加上替换交易自身大小的增量中继费
+// 2. 新费率要高于原交易的费率
+// 3. 不能新增未确认的输入
+// 4. 原交易至少有一个输入声明了可替换，详见 CheckReplaceable
+// 手续费优先从原交易的找零里扣，找零变成灰尘时就去掉找零，找零不够时再追加输入
+// 详见 https://github.com/bitcoin/bips/blob/master/bip-0125.mediawiki
+func BumpFee(param *BumpFeeParam, netParams *chaincfg.Params) (*BumpFeeResult, error) {
+	if param.OrigTx == nil || param.OrigParams == nil {
+		return nil, errors.New("wrong bump-fee param orig-tx or orig-params is none")
+	}
+	origParams := param.OrigParams
+	origFee, err := checkReplacement(param.OrigTx, origParams, param.FeeRatePerKb, netParams)
+	if err != nil {
+		return nil, err
+	}
+	if param.ChangeIndex >= len(origParams.OutList) {
+		return nil, errors.Errorf("wrong change-index=%d out-list-size=%d", param.ChangeIndex, len(origParams.OutList))
+	}
+
+	var spentMap = make(map[wire.OutPoint]bool, len(origParams.VinList))
+	for _, vin := range origParams.VinList {
+		spentMap[vin.OutPoint] = true
+	}
+	var extraVinList = make([]VinType, 0, len(param.ExtraVinList))
+	for _, vin := range param.ExtraVinList {
+		if vin.Unconfirmed {
+			return nil, errors.Errorf("wrong extra-vin %v is unconfirmed, replacement cannot add unconfirmed inputs", vin.OutPoint)
+		}
+		if spentMap[vin.OutPoint] {
+			return nil, errors.Errorf("wrong extra-vin %v is already spent by orig-tx", vin.OutPoint)
+		}
+		spentMap[vin.OutPoint] = true
+		extraVinList = append(extraVinList, vin)
+	}
+	sort.SliceStable(extraVinList, func(i, j int) bool {
+		return extraVinList[i].Amount > extraVinList[j].Amount
+	})
+
+	//去掉原来的找零，由 ChangeBuilder 重新计算找零
+	var change = param.ChangeTo
+	var outList = make([]OutType, 0, len(origParams.OutList))
+	for idx, output := range origParams.OutList {
+		if idx == param.ChangeIndex {
+			pkScript, err := output.GetPkScript(netParams)
+			if err != nil {
+				return nil, errors.WithMessage(err, "wrong change.address->pk-script")
+			}
+			change = &ChangeTo{PkScript: pkScript}
+			continue
+		}
+		outList = append(outList, output)
+	}
+
+	builder := &ChangeBuilder{
+		ChangeTo:      change,
+		FeeRatePerKb:  param.FeeRatePerKb,
+		DustFee:       param.DustFee,
+		DustLimit:     param.DustLimit,
+		RelayFeePerKb: param.RelayFeePerKb,
+		minFee:        newReplacementMinFee(origFee, param.GetIncrementalRelayFeePerKb()),
+	}
+
+	//复制原交易的全部参数，包括排列方式，再换上去掉找零以后的输出
+	txParams := origParams.Clone()
+	txParams.OutList = outList
+	var res *ChangeResult
+	for {
+		var err error
+		res, err = builder.Build(txParams, netParams)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrInsufficientFunds) || len(extraVinList) == 0 {
+			return nil, errors.WithMessage(err, "wrong build-with-change")
+		}
+		//找零不够扣，就追加输入再试
+		txParams.VinList = append(txParams.VinList, extraVinList[0])
+		extraVinList = extraVinList[1:]
+	}
+
+	var changeIndex = res.ChangeIndex
+	if res.HasChange() && param.ChangeIndex >= 0 {
+		//把找零放回原来的位置，这样原交易的各个输出在替换交易里的位置都不变
+		outs := res.TxParams.OutList
+		changeOut := outs[changeIndex]
+		copy(outs[param.ChangeIndex+1:], outs[param.ChangeIndex:changeIndex])
+		outs[param.ChangeIndex] = changeOut
+		changeIndex = param.ChangeIndex
+	}
+	return &BumpFeeResult{
+		TxParams:     res.TxParams,
+		Fee:          res.Fee,
+		OrigFee:      origFee,
+		ChangeAmount: res.ChangeAmount,
+		ChangeIndex:  changeIndex,
+	}, nil
+}
+
+// checkReplacement 检查原交易是否可以被替换，以及新费率是否高于原交易的费率，返回原交易的手续费
+func checkReplacement(origTx *wire.MsgTx, origParams *BitcoinTxParams, feeRatePerKb btcutil.Amount, netParams *chaincfg.Params) (btcutil.Amount, error) {
+	if err := CheckReplaceable(origTx); err != nil {
+		return 0, err
+	}
+	if err := origParams.CheckMsgTxParam(origTx, netParams); err != nil {
+		return 0, errors.WithMessage(err, "wrong orig-params not match orig-tx")
+	}
+	origFee := origParams.GetFee()
+	origVSize := GetMsgTxVSize(origTx)
+	//新费率需要高于原交易的费率，这里按原交易的大小比较
+	if newFee := feeForVSizeCeil(feeRatePerKb, origVSize); newFee <= int64(origFee) {
+		return 0, errors.Errorf("wrong fee-rate=%d not higher than orig-fee-rate orig-fee=%d orig-v-size=%d", feeRatePerKb, origFee, origVSize)
+	}
+	return origFee, nil
+}
+
+// newReplacementMinFee 替换交易的手续费下限，不低于原手续费加上替换交易自身大小的增量中继费
+func newReplacementMinFee(origFee btcutil.Amount, incrementalFeePerKb btcutil.Amount) func(vSize int) btcutil.Amount {
+	return func(vSize int) btcutil.Amount {
+		return origFee + btcutil.Amount(feeForVSizeCeil(incrementalFeePerKb, vSize))
+	}
+}
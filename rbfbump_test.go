@@ -0,0 +1,256 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// caseNewBumpFeeOrigTx 拼出并签名原交易，再像从链上拿到交易那样通过 hex 反拼出原交易的参数
+func caseNewBumpFeeOrigTx(t *testing.T, amount int64, feeRatePerKb btcutil.Amount) (*wire.MsgTx, *BitcoinTxParams, int) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+				RBFInfo:  *NewRBFActive(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: amount,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, feeRatePerKb, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	require.True(t, res.HasChange())
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+
+	txHex, err := CvtMsgTxToHex(signParam.MsgTx)
+	require.NoError(t, err)
+	msgTx, err := NewMsgTxFromHex(txHex)
+	require.NoError(t, err)
+
+	preMap := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		*MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2): NewSenderAmountUtxo(NewAddressTuple(senderAddress), 13089),
+	})
+	origParams, err := NewCustomParamFromMsgTx(msgTx, preMap)
+	require.NoError(t, err)
+	return msgTx, origParams, res.ChangeIndex
+}
+
+func TestBumpFee(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, changeIndex := caseNewBumpFeeOrigTx(t, 1234, 1000)
+
+	res, err := BumpFee(&BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  changeIndex,
+		FeeRatePerKb: 5000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+	}, &netParams)
+	require.NoError(t, err)
+	require.Equal(t, changeIndex, res.ChangeIndex)
+	require.Equal(t, origParams.OutList[0], res.TxParams.OutList[0]) //转账目标不变
+	require.Equal(t, res.Fee, res.TxParams.GetFee())
+	require.Equal(t, origParams.GetFee()-res.Fee+btcutil.Amount(origParams.OutList[changeIndex].Amount), res.ChangeAmount)
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+
+	// BIP125 的规则，新手续费不低于原手续费加上增量中继费
+	vSize := GetMsgTxVSize(signParam.MsgTx)
+	require.GreaterOrEqual(t, int64(res.Fee), int64(res.OrigFee)+int64(vSize))
+	require.Greater(t, int64(res.Fee)*int64(GetMsgTxVSize(origTx)), int64(res.OrigFee)*int64(vSize))
+	t.Log("orig-fee:", res.OrigFee, "new-fee:", res.Fee, "v-size:", vSize)
+}
+
+func TestBumpFee_IncrementalRelayFee(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, changeIndex := caseNewBumpFeeOrigTx(t, 1234, 5000)
+
+	// 新费率只比原费率高一点点，这时需要满足的是增量中继费的要求
+	res, err := BumpFee(&BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  changeIndex,
+		FeeRatePerKb: 5100,
+	}, &netParams)
+	require.NoError(t, err)
+
+	vSize, err := res.TxParams.EstimateTxSize(&netParams, NewNoChange())
+	require.NoError(t, err)
+	require.Equal(t, res.OrigFee+btcutil.Amount(vSize), res.Fee)
+}
+
+func TestBumpFee_AddInput(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, changeIndex := caseNewBumpFeeOrigTx(t, 12000, 1000) //找零很少
+
+	param := &BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  changeIndex,
+		FeeRatePerKb: 20000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+	}
+	_, err := BumpFee(param, &netParams)
+	require.ErrorIs(t, err, ErrInsufficientFunds) //找零不够扣
+
+	param.ExtraVinList = []VinType{
+		{
+			OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+			Sender:   *NewAddressTuple(senderAddress),
+			Amount:   4900,
+			RBFInfo:  *NewRBFActive(),
+		},
+	}
+	res, err := BumpFee(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxParams.VinList, 2)
+	require.Equal(t, res.Fee, res.TxParams.GetFee())
+	t.Log("orig-fee:", res.OrigFee, "new-fee:", res.Fee, "change:", res.ChangeAmount)
+
+	// 不能新增未确认的输入
+	param.ExtraVinList[0].Unconfirmed = true
+	_, err = BumpFee(param, &netParams)
+	require.Error(t, err)
+	t.Log(err)
+}
+
+func TestBumpFee_LowerFeeRate(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, changeIndex := caseNewBumpFeeOrigTx(t, 1234, 5000)
+
+	_, err := BumpFee(&BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  changeIndex,
+		FeeRatePerKb: 4000,
+	}, &netParams)
+	require.Error(t, err)
+	t.Log(err)
+}
+
+func TestBumpFee_KeepOrdering(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, changeIndex := caseNewBumpFeeOrigTx(t, 1234, 1000)
+	origParams.Ordering = NewTxOrderingBIP69()
+
+	res, err := BumpFee(&BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  changeIndex,
+		FeeRatePerKb: 5000,
+	}, &netParams)
+	require.NoError(t, err)
+	require.Equal(t, TxOrderBIP69, res.TxParams.Ordering.Mode)
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, res.TxParams.Ordering.check(signParam.MsgTx))
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+}
+
+func TestBumpFee_OtherError(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, _ := caseNewBumpFeeOrigTx(t, 1234, 1000)
+
+	//不是余额不足的错误时直接返回，不会再追加输入
+	_, err := BumpFee(&BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  -1,
+		ChangeTo:     &ChangeTo{PkScript: []byte{0x51}},
+		FeeRatePerKb: 5000,
+		ExtraVinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4900,
+			},
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 1),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4800,
+			},
+		},
+	}, &netParams)
+	require.ErrorIs(t, err, ErrUnsupportedAddressType)
+	t.Log(err)
+}
+
+func TestBumpFee_DropDustChange(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, changeIndex := caseNewBumpFeeOrigTx(t, 12000, 1000) //找零很少
+	require.Equal(t, 1, changeIndex)
+	//把找零放到前面，看看去掉找零以后后面的输出是怎么移动的
+	origTx.TxOut[0], origTx.TxOut[1] = origTx.TxOut[1], origTx.TxOut[0]
+	origParams.OutList[0], origParams.OutList[1] = origParams.OutList[1], origParams.OutList[0]
+	changeIndex = 0
+
+	res, err := BumpFee(&BumpFeeParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		ChangeIndex:  changeIndex,
+		FeeRatePerKb: 6000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+	}, &netParams)
+	require.NoError(t, err)
+	//找零变成灰尘被去掉了，多出来的都作为手续费
+	require.Equal(t, -1, res.ChangeIndex)
+	require.Equal(t, btcutil.Amount(0), res.ChangeAmount)
+	require.Equal(t, origParams.GetFee()+btcutil.Amount(origParams.OutList[changeIndex].Amount), res.Fee)
+	//原来排在找零后面的转账目标前移了一位
+	require.Len(t, res.TxParams.OutList, 1)
+	require.Equal(t, origParams.OutList[1], res.TxParams.OutList[0])
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	t.Log("orig-fee:", res.OrigFee, "new-fee:", res.Fee)
+}
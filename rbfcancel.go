@@ -0,0 +1,108 @@
+package gobtcsign
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// NotReplaceableError 原交易的全部输入都没有声明可替换时返回这个错误，调用方可以使用 errors.As 判断，也可以使用 errors.Is 判断 ErrNotReplaceable
+// 这种交易只能等它被打包，或者等它从内存池里过期
+type NotReplaceableError struct {
+	TxHash chainhash.Hash //原交易的哈希
+}
+
+func (e *NotReplaceableError) Error() string {
+	return fmt.Sprintf("tx-not-replaceable tx=%s", e.TxHash)
+}
+
+func (e *NotReplaceableError) Is(target error) bool {
+	return target == ErrNotReplaceable
+}
+
+// CheckReplaceable 按 BIP125 的规则检查交易是否可以被替换，只要有一个输入的序号表示可替换就行，序号的含义和 RBFConfig 相同
+func CheckReplaceable(msgTx *wire.MsgTx) error {
+	for _, txIn := range msgTx.TxIn {
+		if NewRBFConfig(txIn.Sequence).IsReplaceable() {
+			return nil
+		}
+	}
+	return errors.WithStack(&NotReplaceableError{TxHash: msgTx.TxHash()})
+}
+
+// CancelTxParam 撤销交易的参数，使用相同的输入把钱全部转回自己的地址，让原交易失效
+// 原交易可以通过 NewMsgTxFromHex 和 NewCustomParamFromMsgTx 得到
+type CancelTxParam struct {
+	OrigTx                   *wire.MsgTx      //原交易，已经签名的，需要声明可替换
+	OrigParams               *BitcoinTxParams //原交易的参数，需要包含每个输入的数量，以计算原交易的手续费
+	Target                   AddressTuple     //接收退回资金的地址，是钱包自己控制的地址
+	FeeRatePerKb             btcutil.Amount   //新的费率，单位是 聪/千字节，需要高于原交易的费率
+	IncrementalRelayFeePerKb btcutil.Amount   //增量中继费率，为0时使用默认的 DefaultIncrementalRelayFeePerKb
+	DustFee                  DustFee          //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit                *DustLimit       //灰尘判定规则，为空时使用比特币的规则
+	RelayFeePerKb            btcutil.Amount   //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+}
+
+// GetIncrementalRelayFeePerKb 获得增量中继费率
+func (param *CancelTxParam) GetIncrementalRelayFeePerKb() btcutil.Amount {
+	if param.IncrementalRelayFeePerKb > 0 {
+		return param.IncrementalRelayFeePerKb
+	}
+	return DefaultIncrementalRelayFeePerKb
+}
+
+// BuildCancelTx 拼出撤销交易，花费原交易的全部输入，只有一个转回自己的输出，手续费满足 BIP125 的规则
+// 当退回的数量扣掉手续费以后是灰尘时返回 SweepDustError 错误
+func BuildCancelTx(param *CancelTxParam, netParams *chaincfg.Params) (*BumpFeeResult, error) {
+	if param.OrigTx == nil || param.OrigParams == nil {
+		return nil, errors.New("wrong cancel-tx param orig-tx or orig-params is none")
+	}
+	origFee, err := checkReplacement(param.OrigTx, param.OrigParams, param.FeeRatePerKb, netParams)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := param.Target.GetPkScript(netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong target.address->pk-script")
+	}
+
+	//没有转账目标，全部输入都作为找零转回自己
+	builder := &ChangeBuilder{
+		ChangeTo:      &ChangeTo{PkScript: pkScript},
+		FeeRatePerKb:  param.FeeRatePerKb,
+		DustFee:       param.DustFee,
+		DustLimit:     param.DustLimit,
+		RelayFeePerKb: param.RelayFeePerKb,
+		minFee:        newReplacementMinFee(origFee, param.GetIncrementalRelayFeePerKb()),
+	}
+	//复制原交易的全部参数，包括排列方式和安全检查，再去掉全部输出
+	txParams := param.OrigParams.Clone()
+	txParams.OutList = []OutType{}
+	res, err := builder.Build(txParams, netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong build-with-change")
+	}
+	if !res.HasChange() {
+		//退回的输出是灰尘被并入了手续费，这时交易没有输出，是不能用的
+		fee, err := builder.estimateTxFee(txParams, netParams, builder.ChangeTo)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-tx-fee")
+		}
+		return nil, &SweepDustError{
+			InputAmount: txParams.GetFee(),
+			Fee:         fee,
+			Amount:      txParams.GetFee() - fee,
+		}
+	}
+	return &BumpFeeResult{
+		TxParams:     res.TxParams,
+		Fee:          res.Fee,
+		OrigFee:      origFee,
+		ChangeAmount: res.ChangeAmount,
+		ChangeIndex:  res.ChangeIndex,
+	}, nil
+}
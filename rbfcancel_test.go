@@ -0,0 +1,99 @@
+package gobtcsign
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCancelTx(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, _ := caseNewBumpFeeOrigTx(t, 1234, 1000)
+
+	res, err := BuildCancelTx(&CancelTxParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		Target:       *NewAddressTuple(senderAddress),
+		FeeRatePerKb: 2000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+	}, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxParams.OutList, 1)
+	require.Equal(t, 0, res.ChangeIndex)
+	require.Equal(t, origParams.VinList, res.TxParams.VinList) //花费相同的输入
+	require.Equal(t, btcutil.Amount(13089)-res.Fee, res.ChangeAmount)
+
+	signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, res.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+
+	vSize := GetMsgTxVSize(signParam.MsgTx)
+	require.GreaterOrEqual(t, int64(res.Fee), int64(res.OrigFee)+int64(vSize))
+	t.Log("orig-fee:", res.OrigFee, "new-fee:", res.Fee, "v-size:", vSize)
+}
+
+func TestBuildCancelTx_KeepParams(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, _ := caseNewBumpFeeOrigTx(t, 1234, 1000)
+	origParams.Ordering = NewTxOrderingBIP69()
+	origParams.FeeGuard = &FeeGuard{MaxFee: 100}
+
+	res, err := BuildCancelTx(&CancelTxParam{
+		OrigTx:       origTx,
+		OrigParams:   origParams,
+		Target:       *NewAddressTuple(senderAddress),
+		FeeRatePerKb: 2000,
+	}, &netParams)
+	require.NoError(t, err)
+	require.Equal(t, origParams.Ordering, res.TxParams.Ordering)
+	require.Equal(t, origParams.FeeGuard, res.TxParams.FeeGuard)
+
+	//原交易的安全检查仍然有效，手续费超过了上限
+	_, err = res.TxParams.CreateTxSignParams(&netParams)
+	require.ErrorIs(t, err, ErrFeeGuard)
+}
+
+func TestCheckReplaceable(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	origTx, origParams, _ := caseNewBumpFeeOrigTx(t, 1234, 1000)
+	require.NoError(t, CheckReplaceable(origTx))
+
+	msgTx := origTx.Copy()
+	msgTx.TxIn[0].Sequence = wire.MaxTxInSequenceNum - 1 //不声明可替换
+	err := CheckReplaceable(msgTx)
+	require.ErrorIs(t, err, ErrNotReplaceable)
+	var notReplaceableErr *NotReplaceableError
+	require.True(t, errors.As(err, &notReplaceableErr))
+	require.Equal(t, msgTx.TxHash(), notReplaceableErr.TxHash)
+
+	origParams.VinList[0].RBFInfo = *NewRBFConfig(wire.MaxTxInSequenceNum - 1)
+	_, err = BuildCancelTx(&CancelTxParam{
+		OrigTx:       msgTx,
+		OrigParams:   origParams,
+		Target:       *NewAddressTuple("tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"),
+		FeeRatePerKb: 2000,
+	}, &netParams)
+	require.True(t, errors.As(err, &notReplaceableErr))
+
+	_, err = BumpFee(&BumpFeeParam{
+		OrigTx:       msgTx,
+		OrigParams:   origParams,
+		ChangeIndex:  1,
+		FeeRatePerKb: 2000,
+	}, &netParams)
+	require.True(t, errors.As(err, &notReplaceableErr))
+}
@@ -28,3 +28,8 @@ func (cfg *RBFConfig) GetSequence() uint32 {
 	}
 	return wire.MaxTxInSequenceNum //当两个元素都为零值时表示不启用RBF机制-因此这里使用默认的最大值表示不启用
 }
+
+// IsReplaceable 按 BIP125 的规则，序号小于 wire.MaxTxInSequenceNum-1 的输入表示这个交易可以被替换
+func (cfg *RBFConfig) IsReplaceable() bool {
+	return cfg.GetSequence() < wire.MaxTxInSequenceNum-1
+}
@@ -77,3 +77,17 @@ func TestRBFConfig_GetSequence_AllowRBFFalseWithSequenceEqualToMaxTxInSequenceNu
 	cfg := &RBFConfig{AllowRBF: false, Sequence: wire.MaxTxInSequenceNum}
 	require.Equal(t, wire.MaxTxInSequenceNum, cfg.GetSequence()) // 由于 Sequence 为 MaxTxInSequenceNum，应该返回 MaxTxInSequenceNum
 }
+
+func TestRBFConfig_IsReplaceable(t *testing.T) {
+	require.True(t, NewRBFActive().IsReplaceable())
+	require.True(t, NewRBFConfig(0).IsReplaceable())
+	require.False(t, NewRBFNotUse().IsReplaceable())
+	require.False(t, (&RBFConfig{}).IsReplaceable())
+}
+
+func TestRBFConfig_IsReplaceable_MaxTxInSequenceNumMinusOne(t *testing.T) {
+	// 测试 Sequence 为 wire.MaxTxInSequenceNum-1 时，虽然 AllowRBF 是 true，但按 BIP125 的规则是不可替换的
+	cfg := NewRBFConfig(wire.MaxTxInSequenceNum - 1)
+	require.Equal(t, true, cfg.AllowRBF)
+	require.Equal(t, false, cfg.IsReplaceable())
+}
@@ -0,0 +1,100 @@
+package gobtcsign
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// RelativeLock BIP68 相对时间锁，表示UTXO被确认以后还要再等多少个区块（或者多少时间）才能被花费
+// 它会被编码到输入的序号里，而且只有交易版本>=2时才生效，详见 https://github.com/bitcoin/bips/blob/master/bip-0068.mediawiki
+// 注意相对时间锁的序号一定小于 wire.MaxTxInSequenceNum-1，因此按 BIP125 的规则，设置了它的交易也是可替换的
+type RelativeLock struct {
+	IsSeconds bool   //为 false 时按区块数锁定，为 true 时按时间锁定，时间的单位是512秒
+	Value     uint16 //区块数，或者512秒的个数
+}
+
+// NewRelativeLockBlocks 按区块数锁定，UTXO被确认以后至少再过这么多个区块才能花费
+func NewRelativeLockBlocks(blocks uint16) *RelativeLock {
+	return &RelativeLock{IsSeconds: false, Value: blocks}
+}
+
+// NewRelativeLockSeconds 按时间锁定，时间的粒度是512秒，这里向上取整，保证锁定的时间不少于给定值
+func NewRelativeLockSeconds(seconds uint32) (*RelativeLock, error) {
+	const granularity = 1 << wire.SequenceLockTimeGranularity
+	units := (uint64(seconds) + granularity - 1) / granularity
+	if units > wire.SequenceLockTimeMask {
+		return nil, errors.Errorf("wrong relative-lock seconds=%d > max=%d", seconds, wire.SequenceLockTimeMask*granularity)
+	}
+	return &RelativeLock{IsSeconds: true, Value: uint16(units)}, nil
+}
+
+// GetSequence 编码成输入的序号，最高位（禁用标志）是0，按时间锁定时设置第22位
+func (lock *RelativeLock) GetSequence() uint32 {
+	var sequence = uint32(lock.Value)
+	if lock.IsSeconds {
+		sequence |= wire.SequenceLockTimeIsSeconds
+	}
+	return sequence
+}
+
+// BlockStamp 区块的高度和时间，用于判断相对时间锁是否已经到期
+type BlockStamp struct {
+	Height     int32 //区块高度
+	MedianTime int64 //区块的 MTP（前11个区块时间戳的中位数），单位是秒，只有按时间锁定时需要
+}
+
+// CheckMature 检查相对时间锁在下一个区块里是否已经到期
+// utxoBlock 是UTXO被确认的区块，按时间锁定时它的 MedianTime 需要填UTXO所在区块的前一个区块的 MTP，这是 BIP68 的规定
+// tipBlock 是当前最新的区块，交易最早进入的是它的下一个区块
+func (lock *RelativeLock) CheckMature(utxoBlock BlockStamp, tipBlock BlockStamp) error {
+	if lock.IsSeconds {
+		const granularity = 1 << wire.SequenceLockTimeGranularity
+		if matureTime := utxoBlock.MedianTime + int64(lock.Value)*granularity; tipBlock.MedianTime < matureTime {
+			return errors.Errorf("relative-lock immature mature-time=%d tip-median-time=%d", matureTime, tipBlock.MedianTime)
+		}
+		return nil
+	}
+	if matureHeight := utxoBlock.Height + int32(lock.Value); tipBlock.Height+1 < matureHeight {
+		return errors.Errorf("relative-lock immature mature-height=%d next-height=%d", matureHeight, tipBlock.Height+1)
+	}
+	return nil
+}
+
+// HasRelativeLock 是否有输入设置了相对时间锁
+func (param *BitcoinTxParams) HasRelativeLock() bool {
+	for _, input := range param.VinList {
+		if input.RelativeLock != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTxVersion 获得交易版本，设置了 Version 时以它为准，否则当有输入设置了相对时间锁时需要版本2，再否则使用默认的 wire.TxVersion
+func (param *BitcoinTxParams) GetTxVersion() int32 {
+	if param.Version != 0 {
+		return param.Version
+	}
+	if param.HasRelativeLock() {
+		return 2
+	}
+	return wire.TxVersion
+}
+
+// CheckRelativeLocks 检查各个输入的相对时间锁在下一个区块里是否已经到期
+// utxoBlocks 是各个UTXO被确认的区块，没有设置相对时间锁的输入可以不填，设置了的输入没有填时表示UTXO还没被确认
+func (param *BitcoinTxParams) CheckRelativeLocks(utxoBlocks map[wire.OutPoint]BlockStamp, tipBlock BlockStamp) error {
+	for idx, input := range param.VinList {
+		if input.RelativeLock == nil {
+			continue
+		}
+		utxoBlock, ok := utxoBlocks[input.OutPoint]
+		if !ok {
+			return errors.Errorf("input %d utxo=%v relative-lock unconfirmed", idx, input.OutPoint)
+		}
+		if err := input.RelativeLock.CheckMature(utxoBlock, tipBlock); err != nil {
+			return errors.WithMessagef(err, "input %d utxo=%v", idx, input.OutPoint)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelativeLock_GetSequence(t *testing.T) {
+	require.Equal(t, uint32(144), NewRelativeLockBlocks(144).GetSequence())
+	require.Equal(t, blockchain.LockTimeToSequence(false, 144), NewRelativeLockBlocks(144).GetSequence())
+
+	lock, err := NewRelativeLockSeconds(86400) //一天
+	require.NoError(t, err)
+	require.Equal(t, uint16(169), lock.Value) //向上取整 86400/512=168.75
+	require.Equal(t, uint32(wire.SequenceLockTimeIsSeconds|169), lock.GetSequence())
+
+	lock, err = NewRelativeLockSeconds(512 * 10)
+	require.NoError(t, err)
+	require.Equal(t, blockchain.LockTimeToSequence(true, 512*10), lock.GetSequence())
+
+	_, err = NewRelativeLockSeconds(512*0xffff + 1)
+	require.Error(t, err)
+}
+
+func TestRelativeLock_CheckMature(t *testing.T) {
+	lock := NewRelativeLockBlocks(10)
+	require.Error(t, lock.CheckMature(BlockStamp{Height: 100}, BlockStamp{Height: 108}))
+	require.NoError(t, lock.CheckMature(BlockStamp{Height: 100}, BlockStamp{Height: 109})) //下一个区块的高度是110
+
+	lock, err := NewRelativeLockSeconds(512)
+	require.NoError(t, err)
+	require.Error(t, lock.CheckMature(BlockStamp{MedianTime: 1700000000}, BlockStamp{MedianTime: 1700000511}))
+	require.NoError(t, lock.CheckMature(BlockStamp{MedianTime: 1700000000}, BlockStamp{MedianTime: 1700000512}))
+}
+
+func TestBitcoinTxParams_RelativeLock(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint:     *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:       *NewAddressTuple(senderAddress),
+				Amount:       4900,
+				RBFInfo:      *NewRBFActive(),
+				RelativeLock: NewRelativeLockBlocks(6),
+			},
+			{
+				OutPoint: *MustNewOutPoint("fcc889d7f0217694ab46d93f03a200d326c34e317552a6a33cb3fab03aa0b439", 1),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4320,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 9000,
+			},
+		},
+		RBFInfo: *NewRBFNotUse(),
+	}
+	require.Equal(t, int32(2), param.GetTxVersion())
+
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), signParam.MsgTx.Version)
+	require.Equal(t, uint32(6), signParam.MsgTx.TxIn[0].Sequence) //相对时间锁优先于RBF的序号
+	require.Equal(t, wire.MaxTxInSequenceNum, signParam.MsgTx.TxIn[1].Sequence)
+
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, param.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	utxoBlocks := map[wire.OutPoint]BlockStamp{
+		param.VinList[0].OutPoint: {Height: 1000},
+	}
+	require.Error(t, param.CheckRelativeLocks(utxoBlocks, BlockStamp{Height: 1004}))
+	require.NoError(t, param.CheckRelativeLocks(utxoBlocks, BlockStamp{Height: 1005}))
+	require.Error(t, param.CheckRelativeLocks(map[wire.OutPoint]BlockStamp{}, BlockStamp{Height: 1005}))
+
+	// 没有相对时间锁时使用默认的交易版本
+	param.VinList[0].RelativeLock = nil
+	require.Equal(t, int32(wire.TxVersion), param.GetTxVersion())
+}
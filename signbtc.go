@@ -18,10 +18,24 @@ type SignParam struct {
 	MsgTx     *wire.MsgTx   // 既是参数也是返回值：输入时签名前的交易，而最终返回也是在这里，会得到签名后的交易
 	InputOuts []*wire.TxOut // 在其它的教程里是 pkScripts [][]byte 和 amounts []int64 两个属性，这里合二为一以保持逻辑简洁，使用 NewInputOuts 或 NewInputOutsV2 即可把两个数组合起来
 	NetParams *chaincfg.Params
+	FeeGuard  *FeeGuard // 签名前的安全检查，为空时使用默认的 NewFeeGuard()，CreateTxSignParams 会把交易参数里的带过来
+	// OutList 里每个输出在交易里的位置，CreateTxSignParams 按 Ordering 排列输出以后设置，为空时表示和 OutList 的位置相同
+	TxOutIndexes []int
+}
+
+// GetTxOutIndex 把 OutList 里的位置（比如 ChangeResult.ChangeIndex）换成交易里的位置，-1 表示没有这个输出，仍然返回-1
+func (param *SignParam) GetTxOutIndex(outIndex int) int {
+	if outIndex < 0 || outIndex >= len(param.TxOutIndexes) {
+		return outIndex
+	}
+	return param.TxOutIndexes[outIndex]
 }
 
 // Sign 根据钱包地址和钱包私钥签名
 func Sign(senderAddress string, privateKeyHex string, param *SignParam) error {
+	if err := param.CheckFeeGuard(); err != nil {
+		return errors.WithMessage(err, "wrong fee-guard")
+	}
 	privKeyBytes, err := hex.DecodeString(privateKeyHex)
 	if err != nil {
 		return errors.WithMessage(err, "wrong decode private key string")
@@ -52,7 +66,7 @@ func Sign(senderAddress string, privateKeyHex string, param *SignParam) error {
 			return errors.WithMessage(err, "wrong sign")
 		}
 	default: //其它钱包类型暂不支持
-		return errors.Errorf("wrong from address=%s address_type=%s not-support-this-address-type", address, reflect.TypeOf(address).String()) //倒是没必要支持太多的类型
+		return errors.WithMessagef(ErrUnsupportedAddressType, "wrong from address=%s address_type=%s not-support-this-address-type", address, reflect.TypeOf(address).String()) //倒是没必要支持太多的类型
 	}
 	return nil
 }
@@ -68,6 +82,9 @@ func SignP2WPKH(signParam *SignParam, privKey *btcec.PrivateKey, compress bool)
 
 	// 接下来可以继续使用 sigHashes 进行签名
 	for idx := range msgTx.TxIn {
+		if IsPayToAnchor(signParam.InputOuts[idx].PkScript) { //P2A输入不需要签名
+			continue
+		}
 		// 计算见证 P2WPKH 地址，通常使用压缩公钥
 		witness, err := txscript.WitnessSignature(msgTx, sigHashes, idx, signParam.InputOuts[idx].Value, signParam.InputOuts[idx].PkScript, txscript.SigHashAll, privKey, compress)
 		if err != nil {
@@ -79,25 +96,53 @@ func SignP2WPKH(signParam *SignParam, privKey *btcec.PrivateKey, compress bool)
 	return VerifySign(msgTx, signParam.InputOuts, prevOutFetcher, sigHashes)
 }
 
+// checkInputOuts 检查每个输入都有前置输出，个数不够时返回 *InputIndexError，有空的前置输出时返回 ErrInputOutMissing
+func checkInputOuts(msgTx *wire.MsgTx, inputOuts []*wire.TxOut) error {
+	if len(inputOuts) < len(msgTx.TxIn) {
+		return errors.WithStack(&InputIndexError{Index: len(msgTx.TxIn) - 1, Count: len(inputOuts)})
+	}
+	for idx := range msgTx.TxIn {
+		if inputOuts[idx] == nil {
+			return errors.WithMessagef(ErrInputOutMissing, "wrong input-outs[%d] is none", idx)
+		}
+	}
+	return nil
+}
+
 func VerifySign(msgTx *wire.MsgTx, inputOuts []*wire.TxOut, prevOutFetcher txscript.PrevOutputFetcher, sigHashes *txscript.TxSigHashes) error {
 	sigCache := txscript.NewSigCache(uint(len(msgTx.TxIn))) //设置为输入的长度是较好的，当然，更大量的计算时也可使用全局的cache
 
-	if len(inputOuts) < len(msgTx.TxIn) { //在底下的逻辑里虽然也能保证，但在这里做一次判断能避免panic，也能避免哪次重构后遗漏这个隐含的条件，因此认为在这里增加个断言还是很有必要的
-		return errors.New("wrong param-outs-length")
+	if err := checkInputOuts(msgTx, inputOuts); err != nil { //在底下的逻辑里虽然也能保证，但在这里做一次判断能避免panic，也能避免哪次重构后遗漏这个隐含的条件，因此认为在这里增加个断言还是很有必要的
+		return err
 	}
 
 	for idx := range msgTx.TxIn { // 这段代码的作用是创建和执行脚本引擎，用于验证指定的脚本是否有效。如果脚本验证失败，则返回错误信息。这在比特币交易的验证过程中非常重要，以确保交易的合法性和安全性。
-		vm, err := txscript.NewEngine(inputOuts[idx].PkScript, msgTx, idx, txscript.StandardVerifyFlags, sigCache, sigHashes, inputOuts[idx].Value, prevOutFetcher)
-		if err != nil {
-			return errors.WithMessagef(err, "wrong new-vm-engine. index=%d", idx)
-		}
-		if err = vm.Execute(); err != nil {
-			return errors.WithMessagef(err, "wrong check-sign-vm-execute. index=%d", idx)
+		if err := verifyInputSignWithCache(msgTx, idx, inputOuts[idx], prevOutFetcher, sigHashes, sigCache); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// verifyInputSign 只验证单个输入的签名，用于交易里的其它输入还没签名的场景
+func verifyInputSign(msgTx *wire.MsgTx, idx int, inputOut *wire.TxOut, prevOutFetcher txscript.PrevOutputFetcher, sigHashes *txscript.TxSigHashes) error {
+	return verifyInputSignWithCache(msgTx, idx, inputOut, prevOutFetcher, sigHashes, nil)
+}
+
+func verifyInputSignWithCache(msgTx *wire.MsgTx, idx int, inputOut *wire.TxOut, prevOutFetcher txscript.PrevOutputFetcher, sigHashes *txscript.TxSigHashes, sigCache *txscript.SigCache) error {
+	if IsPayToAnchor(inputOut.PkScript) { //P2A输入没有签名，而且 btcd 的标准规则不认识它，因此不走脚本引擎
+		return checkAnchorInputSign(msgTx.TxIn[idx], idx)
+	}
+	vm, err := txscript.NewEngine(inputOut.PkScript, msgTx, idx, txscript.StandardVerifyFlags, sigCache, sigHashes, inputOut.Value, prevOutFetcher)
+	if err != nil {
+		return errors.WithStack(newVerifyError(idx, err, "wrong new-vm-engine"))
+	}
+	if err = vm.Execute(); err != nil {
+		return errors.WithStack(newVerifyError(idx, err, "wrong check-sign-vm-execute"))
+	}
+	return nil
+}
+
 // 创建和填充 prevOuts（前置输出映射）
 func newPrevOutsMap(signParam *SignParam) map[wire.OutPoint]*wire.TxOut {
 	var prevOutsMap = make(map[wire.OutPoint]*wire.TxOut, len(signParam.MsgTx.TxIn))
@@ -129,13 +174,16 @@ func CheckPKHAddressIsCompress(defaultNet *chaincfg.Params, publicKey *btcec.Pub
 			return compress, nil
 		}
 	}
-	return false, errors.Errorf("unknown address type. address=%s", senderAddress)
+	return false, errors.WithMessagef(ErrUnsupportedAddressType, "unknown address type. address=%s", senderAddress)
 }
 
 func SignP2PKH(signParam *SignParam, privKey *btcec.PrivateKey, compress bool) error {
 	var msgTx = signParam.MsgTx // 这里是指针传递，因此这个既是参数也是返回值
 
 	for idx := range msgTx.TxIn {
+		if IsPayToAnchor(signParam.InputOuts[idx].PkScript) { //P2A输入不需要签名
+			continue
+		}
 		// 使用私钥对交易输入进行签名
 		// 在大多数情况下，使用压缩公钥是可以接受的，并且更常见。压缩公钥可以减小交易的大小，从而降低交易费用，并且在大多数情况下，与非压缩公钥相比，安全性没有明显的区别
 		signatureScript, err := txscript.SignatureScript(msgTx, idx, signParam.InputOuts[idx].PkScript, txscript.SigHashAll, privKey, compress)
@@ -155,45 +203,102 @@ func SignP2PKH(signParam *SignParam, privKey *btcec.PrivateKey, compress bool) e
 }
 
 // CheckMsgTxParam 当签完名以后最好是再用这个函数检查检查，避免签名逻辑在有BUG时修改输入或输出的内容
+// 当配置了重新排列时，输入按 OutPoint 匹配，输出按脚本和数量匹配，而 BIP69 还会检查交易是否已经排好序
+// 锁定时间和交易版本只在参数里设置了时才比较，这样零值的参数也能检查外部拼出的交易，比如版本2的交易
 func (param *BitcoinTxParams) CheckMsgTxParam(msgTx *wire.MsgTx, netParams *chaincfg.Params) error {
+	// 验证锁定时间是否匹配
+	if param.LockTime != 0 && msgTx.LockTime != param.LockTime {
+		return errors.WithMessagef(ErrMsgTxMismatch, "lock-time mismatch: got %d, expected %d", msgTx.LockTime, param.LockTime)
+	}
+	// 验证交易版本是否匹配，没有设置版本时，有相对时间锁的交易版本至少是2，否则 BIP68 不生效
+	if param.Version != 0 && msgTx.Version != param.Version {
+		return errors.WithMessagef(ErrMsgTxMismatch, "version mismatch: got %d, expected %d", msgTx.Version, param.Version)
+	}
+	if param.Version == 0 && param.HasRelativeLock() && msgTx.Version < 2 {
+		return errors.WithMessagef(ErrMsgTxMismatch, "version mismatch: got %d, relative-lock needs >= 2", msgTx.Version)
+	}
+	if !param.Ordering.IsKeep() {
+		return param.checkMsgTxParamUnordered(msgTx, netParams)
+	}
 	// 验证输入的长度是否匹配
 	if len(msgTx.TxIn) != len(param.VinList) {
-		return errors.Errorf("input count mismatch: got %d, expected %d", len(msgTx.TxIn), len(param.VinList))
+		return errors.WithMessagef(ErrMsgTxMismatch, "input count mismatch: got %d, expected %d", len(msgTx.TxIn), len(param.VinList))
 	}
 	// 验证每个输入的哈希和位置是否匹配
 	for idx, txVin := range msgTx.TxIn {
 		input := param.VinList[idx]
 		// 检查 UTXO 的 OutPoint 是否匹配
 		if txVin.PreviousOutPoint.Hash != input.OutPoint.Hash {
-			return errors.Errorf("input %d outpoint-hash mismatch: got %v, expected %v", idx, txVin.PreviousOutPoint.Hash, input.OutPoint.Hash)
+			return errors.WithMessagef(ErrMsgTxMismatch, "input %d outpoint-hash mismatch: got %v, expected %v", idx, txVin.PreviousOutPoint.Hash, input.OutPoint.Hash)
 		}
 		// 检查在交易输出中的位置是否完全匹配
 		if txVin.PreviousOutPoint.Index != input.OutPoint.Index {
-			return errors.Errorf("input %d outpoint-index mismatch: got %v, expected %v", idx, txVin.PreviousOutPoint.Index, input.OutPoint.Index)
+			return errors.WithMessagef(ErrMsgTxMismatch, "input %d outpoint-index mismatch: got %v, expected %v", idx, txVin.PreviousOutPoint.Index, input.OutPoint.Index)
 		}
 		// 检查 vin 的 RBF 序号是否完全匹配
 		if seqNo := param.GetTxInputSequence(input); seqNo != txVin.Sequence {
-			return errors.Errorf("input %d tx-in-sequence mismatch: got %v, expected %v", idx, txVin.Sequence, seqNo)
+			return errors.WithMessagef(ErrMsgTxMismatch, "input %d tx-in-sequence mismatch: got %v, expected %v", idx, txVin.Sequence, seqNo)
 		}
 	}
 	// 验证输出数量是否匹配
 	if len(msgTx.TxOut) != len(param.OutList) {
-		return errors.Errorf("output count mismatch: got %d, expected %d", len(msgTx.TxOut), len(param.OutList))
+		return errors.WithMessagef(ErrMsgTxMismatch, "output count mismatch: got %d, expected %d", len(msgTx.TxOut), len(param.OutList))
 	}
 	// 验证每个输出的地址和金额是否匹配
 	for idx, txVout := range msgTx.TxOut {
 		output := param.OutList[idx]
 		// 验证输出地址
-		pkScript, err := output.Target.GetPkScript(netParams)
+		pkScript, err := output.GetPkScript(netParams)
 		if err != nil {
-			return errors.Errorf("cannot get pkScript of address %s: %v", output.Target.Address, err)
+			return errors.WithMessagef(err, "cannot get pkScript of address %s", output.Target.Address)
 		}
 		if !bytes.Equal(txVout.PkScript, pkScript) {
-			return errors.Errorf("output %d script mismatch: got %x, expected %x", idx, txVout.PkScript, pkScript)
+			return errors.WithMessagef(ErrAddressPkScriptMismatch, "output %d script mismatch: got %x, expected %x", idx, txVout.PkScript, pkScript)
 		}
 		// 验证输出金额
 		if txVout.Value != output.Amount {
-			return errors.Errorf("output %d amount mismatch: got %d, expected %d", idx, txVout.Value, output.Amount)
+			return errors.WithMessagef(ErrMsgTxMismatch, "output %d amount mismatch: got %d, expected %d", idx, txVout.Value, output.Amount)
+		}
+	}
+	return nil
+}
+
+// checkMsgTxParamUnordered 检查重新排列过的交易，输入和输出的位置可以和参数不同，但内容必须一一对应
+func (param *BitcoinTxParams) checkMsgTxParamUnordered(msgTx *wire.MsgTx, netParams *chaincfg.Params) error {
+	if err := param.Ordering.check(msgTx); err != nil {
+		return errors.WithMessage(err, "wrong tx-ordering")
+	}
+	vinList, err := param.alignVinList(msgTx)
+	if err != nil {
+		return errors.WithMessage(err, "wrong align-vin-list")
+	}
+	for idx, txVin := range msgTx.TxIn {
+		// 检查 vin 的 RBF 序号是否完全匹配
+		if seqNo := param.GetTxInputSequence(vinList[idx]); seqNo != txVin.Sequence {
+			return errors.WithMessagef(ErrMsgTxMismatch, "input %d tx-in-sequence mismatch: got %v, expected %v", idx, txVin.Sequence, seqNo)
+		}
+	}
+	// 验证输出数量是否匹配
+	if len(msgTx.TxOut) != len(param.OutList) {
+		return errors.WithMessagef(ErrMsgTxMismatch, "output count mismatch: got %d, expected %d", len(msgTx.TxOut), len(param.OutList))
+	}
+	outputs, err := param.GetOutputs(netParams)
+	if err != nil {
+		return errors.WithMessage(err, "wrong get-outputs")
+	}
+	// 每个交易输出都要在参数里找到一个脚本和数量都相同的，而且参数里的每个输出只能匹配一次
+	var matched = make([]bool, len(outputs))
+	for idx, txVout := range msgTx.TxOut {
+		var found bool
+		for pos, output := range outputs {
+			if !matched[pos] && output.Value == txVout.Value && bytes.Equal(output.PkScript, txVout.PkScript) {
+				matched[pos] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.WithMessagef(ErrMsgTxMismatch, "output %d mismatch: got script %x amount %d, not in out-list", idx, txVout.PkScript, txVout.Value)
 		}
 	}
 	return nil
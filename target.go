@@ -4,6 +4,7 @@ import (
 	"bytes"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/pkg/errors"
 )
 
@@ -28,7 +29,7 @@ func (one *AddressTuple) GetPkScript(netParams *chaincfg.Params) ([]byte, error)
 			return nil, errors.WithMessage(err, "wrong-address")
 		}
 		if !bytes.Equal(one.PkScript, pkScript) {
-			return nil, errors.New("address-pk-script-mismatch")
+			return nil, errors.WithStack(ErrAddressPkScriptMismatch)
 		}
 		return pkScript, nil
 	}
@@ -38,7 +39,7 @@ func (one *AddressTuple) GetPkScript(netParams *chaincfg.Params) ([]byte, error)
 	if one.Address != "" {
 		return GetAddressPkScript(one.Address, netParams) //这里不用做缓存避免增加复杂度
 	}
-	return nil, errors.New("no-pk-script-no-address")
+	return nil, errors.WithStack(ErrNoPkScriptNoAddress)
 }
 
 func (one *AddressTuple) VerifyMatch(netParams *chaincfg.Params) error {
@@ -48,8 +49,71 @@ func (one *AddressTuple) VerifyMatch(netParams *chaincfg.Params) error {
 			return errors.WithMessage(err, "wrong-address")
 		}
 		if !bytes.Equal(one.PkScript, pkScript) {
-			return errors.New("address-pk-script-mismatch")
+			return errors.WithStack(ErrAddressPkScriptMismatch)
 		}
 	}
 	return nil
 }
+
+// GetAddress 获得钱包地址，当地址存在时就用已有的，否则就根据公钥脚本反推，没有对应的地址时返回 ErrNoAddressPkScript
+func (one *AddressTuple) GetAddress(netParams *chaincfg.Params) (string, error) {
+	if one.Address != "" {
+		return one.Address, nil
+	}
+	if len(one.PkScript) == 0 {
+		return "", errors.WithStack(ErrNoPkScriptNoAddress)
+	}
+	address, _, err := GetPkScriptAddress(one.PkScript, netParams)
+	if err != nil {
+		return "", err
+	}
+	return address, nil
+}
+
+// GetScriptClass 获得脚本的类型，比如 txscript.WitnessV0PubKeyHashTy，不认识的脚本是 txscript.NonStandardTy
+func (one *AddressTuple) GetScriptClass(netParams *chaincfg.Params) (txscript.ScriptClass, error) {
+	pkScript, err := one.GetPkScript(netParams)
+	if err != nil {
+		return txscript.NonStandardTy, err
+	}
+	return txscript.GetScriptClass(pkScript), nil
+}
+
+// Resolve 只有公钥脚本时反推出钱包地址并填写进去，这样展示时就是地址而不是脚本的字节
+// 没有对应地址的脚本保持不变，返回 false，这不算错误
+func (one *AddressTuple) Resolve(netParams *chaincfg.Params) (bool, error) {
+	if one.Address != "" {
+		return true, one.VerifyMatch(netParams)
+	}
+	if len(one.PkScript) == 0 {
+		return false, errors.WithStack(ErrNoPkScriptNoAddress)
+	}
+	address, _, err := GetPkScriptAddress(one.PkScript, netParams)
+	if err != nil {
+		if errors.Is(err, ErrNoAddressPkScript) {
+			return false, nil
+		}
+		return false, err
+	}
+	one.Address = address
+	return true, nil
+}
+
+// GetPkScriptAddress 根据公钥脚本反推钱包地址，同时返回脚本的类型
+// 只有 P2PKH、P2SH、P2WPKH、P2WSH、P2TR 有对应的地址，其它脚本返回 ErrNoAddressPkScript，这时的脚本类型仍然是有效的，非标准脚本是 txscript.NonStandardTy
+// P2PK 虽然能算出 P2PKH 地址，但是那个地址的脚本不是这个脚本，因此也当作没有地址
+func GetPkScriptAddress(pkScript []byte, netParams *chaincfg.Params) (string, txscript.ScriptClass, error) {
+	scriptClass, addresses, _, err := txscript.ExtractPkScriptAddrs(pkScript, netParams)
+	if err != nil {
+		return "", txscript.NonStandardTy, errors.WithMessage(err, "wrong extract-pk-script-addrs")
+	}
+	switch scriptClass {
+	case txscript.PubKeyHashTy, txscript.ScriptHashTy, txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy, txscript.WitnessV1TaprootTy:
+		if len(addresses) != 1 {
+			return "", scriptClass, errors.WithMessagef(ErrNoAddressPkScript, "pk-script class=%s address count=%d", scriptClass, len(addresses))
+		}
+		return addresses[0].EncodeAddress(), scriptClass, nil
+	default:
+		return "", scriptClass, errors.WithMessagef(ErrNoAddressPkScript, "pk-script class=%s", scriptClass)
+	}
+}
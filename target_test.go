@@ -3,7 +3,10 @@ package gobtcsign
 import (
 	"testing"
 
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,3 +65,77 @@ func TestNewAddressTuple_PkScriptPredefined(t *testing.T) {
 	expected := []byte{118, 169, 20, 98, 233, 7, 177, 92, 191, 39, 213, 66, 83, 153, 235, 246, 240, 251, 80, 235, 184, 143, 24, 136, 172}
 	require.Equal(t, expected, pkScript)
 }
+
+func TestGetPkScriptAddress(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	hash20 := make([]byte, 20)
+	hash32 := make([]byte, 32)
+	p2pkh, err := btcutil.NewAddressPubKeyHash(hash20, &netParams)
+	require.NoError(t, err)
+	p2sh, err := btcutil.NewAddressScriptHashFromHash(hash20, &netParams)
+	require.NoError(t, err)
+	p2wsh, err := btcutil.NewAddressWitnessScriptHash(hash32, &netParams)
+	require.NoError(t, err)
+	p2tr, err := btcutil.NewAddressTaproot(hash32, &netParams)
+	require.NoError(t, err)
+
+	for _, address := range []string{
+		"tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap",
+		p2pkh.EncodeAddress(),
+		p2sh.EncodeAddress(),
+		p2wsh.EncodeAddress(),
+		p2tr.EncodeAddress(),
+	} {
+		pkScript, err := GetAddressPkScript(address, &netParams)
+		require.NoError(t, err)
+		res, scriptClass, err := GetPkScriptAddress(pkScript, &netParams)
+		require.NoError(t, err)
+		require.Equal(t, address, res)
+		t.Log(address, scriptClass)
+	}
+
+	//OP_RETURN 没有地址
+	nullData, err := txscript.NullDataScript([]byte("memo"))
+	require.NoError(t, err)
+	_, scriptClass, err := GetPkScriptAddress(nullData, &netParams)
+	require.ErrorIs(t, err, ErrNoAddressPkScript)
+	require.Equal(t, txscript.NullDataTy, scriptClass)
+
+	//非标准脚本
+	_, scriptClass, err = GetPkScriptAddress([]byte{txscript.OP_TRUE}, &netParams)
+	require.ErrorIs(t, err, ErrNoAddressPkScript)
+	require.Equal(t, txscript.NonStandardTy, scriptClass)
+}
+
+func TestBitcoinTxParams_ResolveAddresses(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(2)
+	memo, err := NewNullDataOutput([]byte("memo"))
+	require.NoError(t, err)
+	param.OutList = append(param.OutList, memo)
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+
+	preImp := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		param.VinList[0].OutPoint: NewSenderAmountUtxo(&AddressTuple{PkScript: MustGetPkScript(MustNewAddress(senderAddress, &netParams))}, 13089),
+	})
+	customParam, err := NewCustomParamFromMsgTx(signParam.MsgTx, preImp)
+	require.NoError(t, err)
+	require.Empty(t, customParam.OutList[0].Target.Address)
+
+	require.NoError(t, customParam.ResolveAddresses(&netParams))
+	require.Equal(t, senderAddress, customParam.VinList[0].Sender.Address)
+	require.Equal(t, "tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx", customParam.OutList[0].Target.Address)
+	require.Empty(t, customParam.OutList[1].Target.Address) //OP_RETURN 没有地址
+
+	scriptClass, err := customParam.OutList[0].Target.GetScriptClass(&netParams)
+	require.NoError(t, err)
+	require.Equal(t, txscript.WitnessV0PubKeyHashTy, scriptClass)
+
+	//地址和脚本都有了，仍然能得到同样的脚本
+	require.NoError(t, customParam.CheckMsgTxParam(signParam.MsgTx, &netParams))
+}
@@ -0,0 +1,223 @@
+package gobtcsign
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxBatchTxCount 默认单轮批量转账最多拼出的交易个数，这些交易只花费候选的UTXO，相互之间是独立的
+// 限制个数只是为了控制单轮广播的交易数量和对账的规模，超出的转账目标留到下一轮
+const DefaultMaxBatchTxCount = 25
+
+// DefaultMaxAncestorCount 节点默认的祖先和后代交易个数上限（包含交易自己），和 Bitcoin Core 的 -limitancestorcount/-limitdescendantcount 相同
+// 花费未确认的UTXO时，拼出的交易和它的未确认父交易都受这个限制，超出时节点会拒绝转发
+const DefaultMaxAncestorCount = 25
+
+// BatchPayoutParam 批量转账参数，把大量的转账目标尽量少地拼进几笔交易里
+type BatchPayoutParam struct {
+	Candidates    []VinType      //候选的UTXO列表，每个UTXO至多被一笔交易花费
+	OutList       []OutType      //全部的转账目标，不要包含找零输出
+	ChangeTo      *ChangeTo      //找零信息，每笔交易各自找零
+	FeeRatePerKb  btcutil.Amount //费率，单位是 聪/千字节，和 EstimateTxFee 的参数含义相同
+	DustFee       DustFee        //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit     *DustLimit     //灰尘判定规则，为空时使用比特币的规则
+	RelayFeePerKb btcutil.Amount //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	RBFInfo       RBFConfig      //拼出来的交易的RBF配置
+	Selector      CoinSelector   //每笔交易的选币策略，为空时使用 LargestFirstSelector 以减少输入个数
+	MaxTxVSize    int            //单笔交易的最大 v-size，为0时使用标准交易的上限 MaxStandardTxVSize
+	MaxOutputs    int            //单笔交易的最多转账目标个数（不含找零），为0时不限制
+	MaxTxCount    int            //最多拼出的交易个数，为0时使用 DefaultMaxBatchTxCount，超出的转账目标留到下一轮
+	// 祖先和后代交易个数上限，为0时使用 DefaultMaxAncestorCount，只对未确认（VinType.Unconfirmed）的候选UTXO起作用
+	// 每笔交易的未确认父交易个数加上自己不能超过它，每个未确认父交易被本轮交易花费的次数加上它自己也不能超过它
+	// 这里只知道父交易，看不到父交易更早的祖先，父交易本身还有未确认的祖先时需要调小这个值
+	MaxAncestorCount int
+}
+
+// BatchPayoutTx 批量转账里的一笔交易
+type BatchPayoutTx struct {
+	TxParams    *BitcoinTxParams //拼好的交易参数，可以直接签名
+	OutIndexes  []int            //这笔交易里的转账目标在 BatchPayoutParam.OutList 里的位置，和 TxParams.OutList 的前几个一一对应，而不是交易里的位置
+	Fee         btcutil.Amount   //这笔交易的手续费
+	ChangeIndex int              //找零输出在 TxParams.OutList 里的位置，为-1时表示没有找零输出，设置了 Ordering 时使用 SignParam.GetTxOutIndex 得到在交易里的位置
+}
+
+// BatchPayoutResult 批量转账的结果，用于对账
+type BatchPayoutResult struct {
+	TxList       []*BatchPayoutTx //拼好的交易列表
+	Unpaid       []int            //没有安排的转账目标，是在 BatchPayoutParam.OutList 里的位置
+	UnpaidReason error            //没有安排的原因，超出交易个数上限时是 ErrMaxBatchTxCount，超出祖先个数上限时是 ErrMaxAncestorCount，钱不够时可以使用 errors.Is 判断 ErrInsufficientFunds
+	TotalFee     btcutil.Amount   //全部交易的手续费之和
+}
+
+// GetSelector 获得选币策略
+func (param *BatchPayoutParam) GetSelector() CoinSelector {
+	if param.Selector != nil {
+		return param.Selector
+	}
+	return NewLargestFirstSelector()
+}
+
+// GetMaxTxVSize 获得单笔交易的最大 v-size
+func (param *BatchPayoutParam) GetMaxTxVSize() int {
+	if param.MaxTxVSize > 0 {
+		return param.MaxTxVSize
+	}
+	return MaxStandardTxVSize
+}
+
+// GetMaxAncestorCount 获得祖先和后代交易个数上限
+func (param *BatchPayoutParam) GetMaxAncestorCount() int {
+	if param.MaxAncestorCount > 0 {
+		return param.MaxAncestorCount
+	}
+	return DefaultMaxAncestorCount
+}
+
+// GetMaxTxCount 获得最多拼出的交易个数
+func (param *BatchPayoutParam) GetMaxTxCount() int {
+	if param.MaxTxCount > 0 {
+		return param.MaxTxCount
+	}
+	return DefaultMaxBatchTxCount
+}
+
+// BuildBatchPayout 按顺序把转账目标分批，每批尽量多地放进一笔交易里，再从剩余的候选UTXO里为这批选币和找零
+// 当一批的交易超过 v-size 上限或者剩余的UTXO不够支付时，就把这批减半再试
+// 只剩一个转账目标也不行时就停下来，把它和后面的转账目标都放进 Unpaid，原因在 UnpaidReason 里，已经拼好的交易仍然返回，以便对账
+// 各笔交易只花费候选的UTXO，相互之间是独立的，花费未确认的UTXO时还受 MaxAncestorCount 的限制
+func BuildBatchPayout(param *BatchPayoutParam, netParams *chaincfg.Params) (*BatchPayoutResult, error) {
+	var candidates = append([]VinType{}, param.Candidates...)
+	var pending = make([]int, 0, len(param.OutList))
+	for idx := range param.OutList {
+		pending = append(pending, idx)
+	}
+
+	var spendCounts = make(map[chainhash.Hash]int) //本轮交易花费各个未确认父交易的次数，即父交易在本轮新增的后代个数
+	var res = &BatchPayoutResult{}
+	for len(pending) > 0 {
+		if len(res.TxList) >= param.GetMaxTxCount() {
+			res.Unpaid = append(res.Unpaid, pending...)
+			res.UnpaidReason = errors.WithMessagef(ErrMaxBatchTxCount, "max-tx-count=%d", param.GetMaxTxCount())
+			break
+		}
+		var size = len(pending)
+		if param.MaxOutputs > 0 && size > param.MaxOutputs {
+			size = param.MaxOutputs
+		}
+		//后代个数已经到上限的父交易，它的其它输出在本轮就不能再花费了
+		usable, saturated := param.excludeSaturatedVins(candidates, spendCounts)
+		batchTx, err := param.buildBatchTx(usable, pending[:size], netParams)
+		if err != nil {
+			res.Unpaid = append(res.Unpaid, pending...)
+			if saturated > 0 {
+				err = errors.WithMessagef(ErrMaxAncestorCount, "max-ancestor-count=%d excluded %d unconfirmed candidates: %v", param.GetMaxAncestorCount(), saturated, err)
+			}
+			res.UnpaidReason = errors.WithMessagef(err, "wrong build-batch-tx out-index=%d", pending[0])
+			break
+		}
+		res.TxList = append(res.TxList, batchTx)
+		res.TotalFee += batchTx.Fee
+		for parent := range collectUnconfirmedParents(batchTx.TxParams.VinList) {
+			spendCounts[parent]++
+		}
+		candidates = excludeSpentVins(candidates, batchTx.TxParams.VinList)
+		pending = pending[len(batchTx.OutIndexes):]
+	}
+	return res, nil
+}
+
+// excludeSaturatedVins 去掉后代个数已经到上限的父交易的未确认UTXO，返回剩下的候选和去掉的个数
+func (param *BatchPayoutParam) excludeSaturatedVins(candidates []VinType, spendCounts map[chainhash.Hash]int) ([]VinType, int) {
+	var results = make([]VinType, 0, len(candidates))
+	for _, vin := range candidates {
+		//父交易自己加上本轮已有的后代，再加上这笔新交易，不能超过上限
+		if vin.Unconfirmed && 1+spendCounts[vin.OutPoint.Hash]+1 > param.GetMaxAncestorCount() {
+			continue
+		}
+		results = append(results, vin)
+	}
+	return results, len(candidates) - len(results)
+}
+
+// collectUnconfirmedParents 交易花费的未确认UTXO所在的父交易
+func collectUnconfirmedParents(vinList []VinType) map[chainhash.Hash]bool {
+	var parents = make(map[chainhash.Hash]bool)
+	for _, vin := range vinList {
+		if vin.Unconfirmed {
+			parents[vin.OutPoint.Hash] = true
+		}
+	}
+	return parents
+}
+
+// buildBatchTx 从这批的转账目标开始尝试拼交易，不行时就减半再试，只剩一个转账目标也不行时返回它的错误
+func (param *BatchPayoutParam) buildBatchTx(candidates []VinType, outIndexes []int, netParams *chaincfg.Params) (*BatchPayoutTx, error) {
+	for size := len(outIndexes); ; size /= 2 { //这批太大或者钱不够，就减半再试
+		batchTx, err := param.buildOneTx(candidates, outIndexes[:size], netParams)
+		if err == nil || size == 1 {
+			return batchTx, err
+		}
+	}
+}
+
+// buildOneTx 为一批转账目标选币和找零，拼出一笔不超过 v-size 上限的交易
+func (param *BatchPayoutParam) buildOneTx(candidates []VinType, outIndexes []int, netParams *chaincfg.Params) (*BatchPayoutTx, error) {
+	var outList = make([]OutType, 0, len(outIndexes))
+	for _, idx := range outIndexes {
+		outList = append(outList, param.OutList[idx])
+	}
+	txParams, err := param.GetSelector().SelectCoins(&CoinSelectParam{
+		Candidates:    candidates,
+		OutList:       outList,
+		ChangeTo:      param.ChangeTo,
+		FeeRatePerKb:  param.FeeRatePerKb,
+		DustFee:       param.DustFee,
+		DustLimit:     param.DustLimit,
+		RelayFeePerKb: param.RelayFeePerKb,
+		RBFInfo:       param.RBFInfo,
+	}, netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong select-coins")
+	}
+	//选币结果里已经包含找零输出，因此这里按不找零计算大小
+	size, err := txParams.EstimateTxSize(netParams, NewNoChange())
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong estimate-tx-size")
+	}
+	if size > param.GetMaxTxVSize() {
+		return nil, errors.Errorf("wrong tx-v-size=%d > max-tx-v-size=%d", size, param.GetMaxTxVSize())
+	}
+	//交易自己加上未确认的父交易，不能超过祖先个数上限
+	if parents := collectUnconfirmedParents(txParams.VinList); 1+len(parents) > param.GetMaxAncestorCount() {
+		return nil, errors.WithMessagef(ErrMaxAncestorCount, "wrong unconfirmed-parents=%d max-ancestor-count=%d", len(parents), param.GetMaxAncestorCount())
+	}
+
+	var changeIndex = -1
+	if len(txParams.OutList) > len(outList) {
+		changeIndex = len(outList) //找零输出是追加在末尾的
+	}
+	return &BatchPayoutTx{
+		TxParams:    txParams,
+		OutIndexes:  append([]int{}, outIndexes...),
+		Fee:         txParams.GetFee(),
+		ChangeIndex: changeIndex,
+	}, nil
+}
+
+// excludeSpentVins 从候选列表里去掉已经被花费的UTXO
+func excludeSpentVins(candidates []VinType, spent []VinType) []VinType {
+	var spentMap = make(map[wire.OutPoint]bool, len(spent))
+	for _, vin := range spent {
+		spentMap[vin.OutPoint] = true
+	}
+	var results = make([]VinType, 0, len(candidates))
+	for _, vin := range candidates {
+		if !spentMap[vin.OutPoint] {
+			results = append(results, vin)
+		}
+	}
+	return results
+}
@@ -0,0 +1,202 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+func caseNewBatchPayoutParam(count int) *BatchPayoutParam {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	var outList []OutType
+	for idx := 0; idx < count; idx++ {
+		outList = append(outList, OutType{
+			Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+			Amount: 1000 + int64(idx),
+		})
+	}
+	return &BatchPayoutParam{
+		Candidates:   caseNewConsolidateVinList(senderAddress, []int64{20000, 20000, 20000, 20000, 20000, 20000}),
+		OutList:      outList,
+		ChangeTo:     &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)},
+		FeeRatePerKb: 2000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+		MaxOutputs:   15,
+	}
+}
+
+func TestBuildBatchPayout(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewBatchPayoutParam(40)
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxList, 3)
+	require.Empty(t, res.Unpaid)
+
+	var paid []int
+	var spent = map[string]bool{}
+	for _, batchTx := range res.TxList {
+		require.LessOrEqual(t, len(batchTx.OutIndexes), 15)
+		for idx, outIndex := range batchTx.OutIndexes {
+			require.Equal(t, param.OutList[outIndex], batchTx.TxParams.OutList[idx])
+		}
+		paid = append(paid, batchTx.OutIndexes...)
+
+		require.Equal(t, len(batchTx.OutIndexes), batchTx.ChangeIndex)
+		require.Equal(t, batchTx.Fee, batchTx.TxParams.GetFee())
+		for _, vin := range batchTx.TxParams.VinList {
+			require.False(t, spent[vin.OutPoint.String()]) //每个UTXO只能被花费一次
+			spent[vin.OutPoint.String()] = true
+		}
+
+		signParam, err := batchTx.TxParams.CreateTxSignParams(&netParams)
+		require.NoError(t, err)
+		require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+		require.NoError(t, batchTx.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	}
+	require.Len(t, paid, 40)
+	for idx, outIndex := range paid {
+		require.Equal(t, idx, outIndex)
+	}
+	t.Log("tx-count:", len(res.TxList), "total-fee:", res.TotalFee)
+}
+
+func TestBuildBatchPayout_MaxTxCount(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewBatchPayoutParam(40)
+	param.MaxTxCount = 2
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxList, 2)
+	require.Len(t, res.Unpaid, 10)
+	require.Equal(t, 30, res.Unpaid[0])
+	require.ErrorIs(t, res.UnpaidReason, ErrMaxBatchTxCount)
+	require.NotErrorIs(t, res.UnpaidReason, ErrInsufficientFunds)
+}
+
+func TestBuildBatchPayout_SplitBySize(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewBatchPayoutParam(40)
+	param.MaxOutputs = 0
+	param.MaxTxVSize = 600 //放不下全部的转账目标，需要减半拆分
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Greater(t, len(res.TxList), 1)
+
+	var count int
+	for _, batchTx := range res.TxList {
+		size, err := batchTx.TxParams.EstimateTxSize(&netParams, NewNoChange())
+		require.NoError(t, err)
+		require.LessOrEqual(t, size, 600)
+		count += len(batchTx.OutIndexes)
+	}
+	require.Equal(t, 40, count)
+}
+
+func TestBuildBatchPayout_InsufficientFunds(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewBatchPayoutParam(1)
+	param.OutList[0].Amount = 1000000
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Empty(t, res.TxList)
+	require.Equal(t, []int{0}, res.Unpaid)
+	require.ErrorIs(t, res.UnpaidReason, ErrInsufficientFunds)
+	t.Log(res.UnpaidReason)
+}
+
+func TestBuildBatchPayout_PartialInsufficientFunds(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	//前面的转账目标能拼出交易，第4个的数量超过了剩余的UTXO，这时停下来并保留已经拼好的交易
+	param := caseNewBatchPayoutParam(6)
+	param.MaxOutputs = 1
+	param.OutList[3].Amount = 1000000
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxList, 3)
+	require.Equal(t, []int{3, 4, 5}, res.Unpaid)
+	require.ErrorIs(t, res.UnpaidReason, ErrInsufficientFunds)
+	require.NotErrorIs(t, res.UnpaidReason, ErrMaxBatchTxCount)
+}
+
+func TestBuildBatchPayout_MaxAncestorCount(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewBatchPayoutParam(4)
+	param.MaxOutputs = 1
+	param.MaxAncestorCount = 3
+	//候选的UTXO都是同一笔未确认交易的输出，这笔父交易在本轮最多只能再有2个后代
+	parentHash := param.Candidates[0].OutPoint.Hash
+	for idx := range param.Candidates {
+		param.Candidates[idx].OutPoint.Hash = parentHash
+		param.Candidates[idx].Unconfirmed = true
+	}
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxList, 2)
+	require.Equal(t, []int{2, 3}, res.Unpaid)
+	require.ErrorIs(t, res.UnpaidReason, ErrMaxAncestorCount)
+	t.Log(res.UnpaidReason)
+
+	for _, batchTx := range res.TxList {
+		signParam, err := batchTx.TxParams.CreateTxSignParams(&netParams)
+		require.NoError(t, err)
+		require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+		require.NoError(t, batchTx.TxParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	}
+
+	//确认过的UTXO不受这个限制
+	param = caseNewBatchPayoutParam(4)
+	param.MaxOutputs = 1
+	param.MaxAncestorCount = 3
+	res, err = BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxList, 4)
+	require.Empty(t, res.Unpaid)
+}
+
+func TestBuildBatchPayout_MaxAncestorCount_Parents(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewBatchPayoutParam(1)
+	param.OutList[0].Amount = 30000 //需要花费两个UTXO
+	param.MaxAncestorCount = 2
+	for idx := range param.Candidates {
+		param.Candidates[idx].Unconfirmed = true //每个UTXO来自不同的未确认父交易
+	}
+
+	res, err := BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Empty(t, res.TxList)
+	require.Equal(t, []int{0}, res.Unpaid)
+	require.ErrorIs(t, res.UnpaidReason, ErrMaxAncestorCount)
+
+	param.MaxAncestorCount = 0 //使用默认值
+	res, err = BuildBatchPayout(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, res.TxList, 1)
+	require.Empty(t, res.Unpaid)
+}
@@ -0,0 +1,144 @@
+package gobtcsign
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/pkg/errors"
+)
+
+// ChangeBuilder 自动找零，根据输入和转账目标计算出手续费，再决定是追加找零输出还是把剩余的并入手续费
+// 这是对 EstimateTxFee 里 "找零本身也可能是软灰尘" 的补充，调用方不再需要手动处理找零
+type ChangeBuilder struct {
+	ChangeTo      *ChangeTo                      //找零信息，为空或者两个成员皆为空时表示不找零，剩余的都作为手续费
+	FeeRatePerKb  btcutil.Amount                 //费率，单位是 聪/千字节，和 EstimateTxFee 的参数含义相同
+	DustFee       DustFee                        //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit     *DustLimit                     //灰尘判定规则，为空时使用比特币的规则，找零是灰尘时就不找零而是并入手续费
+	RelayFeePerKb btcutil.Amount                 //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	minFee        func(vSize int) btcutil.Amount //手续费的下限，和交易大小有关，比如 RBF 要求新手续费不低于原手续费加上增量中继费
+}
+
+func NewChangeBuilder(change *ChangeTo, feeRatePerKb btcutil.Amount, dustFee DustFee, dustLimit *DustLimit) *ChangeBuilder {
+	return &ChangeBuilder{
+		ChangeTo:      change,
+		FeeRatePerKb:  feeRatePerKb,
+		DustFee:       dustFee,
+		DustLimit:     dustLimit,
+		RelayFeePerKb: txrules.DefaultRelayFeePerKb,
+	}
+}
+
+// ChangeResult 自动找零的结果
+type ChangeResult struct {
+	TxParams     *BitcoinTxParams //拼好的交易参数，当需要找零时 OutList 的末尾就是找零输出
+	Fee          btcutil.Amount   //交易的手续费，当找零被并入手续费时，这里也包含被并入的数量
+	ChangeAmount btcutil.Amount   //找零的数量，为0时表示没有找零输出
+	ChangeIndex  int              //找零输出在 OutList 里的位置，为-1时表示没有找零输出，设置了 Ordering 时使用 SignParam.GetTxOutIndex 得到在交易里的位置
+}
+
+// HasChange 是否有找零输出
+func (res *ChangeResult) HasChange() bool {
+	return res.ChangeIndex >= 0
+}
+
+// Build 根据交易参数（不含找零输出）计算找零，返回新的交易参数，不会修改传进来的参数
+// 先计算含找零输出时的手续费，当找零本身是软灰尘时还需要再加上找零的软灰尘费用
+// 当找零扣掉这些费用以后是灰尘（或者不够）时，就不找零而是把剩余的都并入手续费
+func (b *ChangeBuilder) Build(param *BitcoinTxParams, netParams *chaincfg.Params) (*ChangeResult, error) {
+	//整体复制再克隆两个列表，这样交易参数里新增的字段也会自动带过来
+	txParams := param.Clone()
+
+	//不找零时的手续费是最低的要求，连这个都不够时说明输入不足
+	feeNoChange, err := b.estimateTxFee(txParams, netParams, NewNoChange())
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong estimate-tx-fee")
+	}
+	surplus := txParams.GetFee() //输入减去输出，就是手续费加找零的总额
+	if surplus < feeNoChange {
+		return nil, errors.WithStack(&InsufficientFundsError{Available: surplus, Required: feeNoChange})
+	}
+
+	change := b.GetChangeTo()
+	changeScript, err := change.GetPkScript()
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong change->pk-script")
+	}
+	if len(changeScript) > 0 {
+		feeWithChange, err := b.estimateTxFee(txParams, netParams, change)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-tx-fee")
+		}
+		changeAmount := surplus - feeWithChange
+		//找零本身也可能是软灰尘，这时还得再交一份找零的软灰尘费用
+		if extraFee := b.DustFee.SumExtraDustFee([]*wire.TxOut{wire.NewTxOut(int64(changeAmount), changeScript)}); changeAmount > 0 && extraFee > 0 {
+			feeWithChange += extraFee
+			changeAmount -= extraFee
+		}
+		if changeAmount > 0 && !b.GetDustLimit().IsDustOutput(wire.NewTxOut(int64(changeAmount), changeScript), b.GetRelayFeePerKb()) {
+			txParams.OutList = append(txParams.OutList, OutType{
+				Target:   AddressTuple{PkScript: changeScript},
+				Amount:   int64(changeAmount),
+				IsChange: true,
+			})
+			return &ChangeResult{
+				TxParams:     txParams,
+				Fee:          feeWithChange,
+				ChangeAmount: changeAmount,
+				ChangeIndex:  len(txParams.OutList) - 1,
+			}, nil
+		}
+	}
+	//不需要找零，或者找零是灰尘，这时剩余的全部作为手续费
+	return &ChangeResult{
+		TxParams:     txParams,
+		Fee:          surplus,
+		ChangeAmount: 0,
+		ChangeIndex:  -1,
+	}, nil
+}
+
+// estimateTxFee 按费率预估手续费，当设置了手续费的下限时，结果不低于下限
+func (b *ChangeBuilder) estimateTxFee(param *BitcoinTxParams, netParams *chaincfg.Params, change *ChangeTo) (btcutil.Amount, error) {
+	fee, err := EstimateTxFee(param, netParams, change, b.FeeRatePerKb, b.DustFee)
+	if err != nil {
+		return 0, err
+	}
+	if b.minFee != nil {
+		size, err := EstimateTxSize(param, netParams, change)
+		if err != nil {
+			return 0, errors.WithMessage(err, "wrong estimate-tx-size")
+		}
+		fee = max(fee, b.minFee(size))
+	}
+	return fee, nil
+}
+
+// GetChangeTo 获得找零信息，当没有设置时表示不找零
+func (b *ChangeBuilder) GetChangeTo() *ChangeTo {
+	if b.ChangeTo != nil {
+		return b.ChangeTo
+	}
+	return NewNoChange()
+}
+
+// GetDustLimit 获得灰尘判定规则，当没有设置时使用比特币的规则
+func (b *ChangeBuilder) GetDustLimit() *DustLimit {
+	if b.DustLimit != nil {
+		return b.DustLimit
+	}
+	return NewDustLimit()
+}
+
+// GetRelayFeePerKb 获得判定灰尘时使用的中继费率
+func (b *ChangeBuilder) GetRelayFeePerKb() btcutil.Amount {
+	if b.RelayFeePerKb > 0 {
+		return b.RelayFeePerKb
+	}
+	return txrules.DefaultRelayFeePerKb
+}
+
+// BuildWithChange 根据找零规则拼出含找零的交易参数，详见 ChangeBuilder 的 Build 逻辑
+func (param *BitcoinTxParams) BuildWithChange(netParams *chaincfg.Params, builder *ChangeBuilder) (*ChangeResult, error) {
+	return builder.Build(param, netParams)
+}
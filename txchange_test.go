@@ -0,0 +1,200 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func TestChangeBuilder_Build(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 1234,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	require.True(t, res.HasChange())
+	require.Equal(t, 1, res.ChangeIndex)
+	require.Len(t, res.TxParams.OutList, 2)
+	require.Len(t, param.OutList, 1) //不修改原来的参数
+
+	fee, err := EstimateTxFee(param, &netParams, change, 1000, NewDustFee())
+	require.NoError(t, err)
+	require.Equal(t, fee, res.Fee)
+	require.Equal(t, fee, res.TxParams.GetFee())
+	require.Equal(t, btcutil.Amount(13089-1234)-fee, res.ChangeAmount)
+	t.Log("fee:", res.Fee, "change:", res.ChangeAmount)
+}
+
+func TestChangeBuilder_Build_DustChange(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 12800, //剩下的不够一个非灰尘的找零
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	require.False(t, res.HasChange())
+	require.Len(t, res.TxParams.OutList, 1)
+	require.Equal(t, btcutil.Amount(289), res.Fee) //找零被并入手续费
+	require.Equal(t, btcutil.Amount(0), res.ChangeAmount)
+}
+
+func TestChangeBuilder_Build_InsufficientFunds(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 13000,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+
+	_, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.Error(t, err)
+	t.Log(err)
+}
+
+func caseNewDogeChangeParam() *BitcoinTxParams {
+	const senderAddress = "nVnVaL5e4L2GDRha9aQ7KiSXDnqjUUz1K4"
+
+	return &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("5ae74f2d6c4a0513e3c75484a726820c2b0653c2b26352afe97f4bf813dcf859", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   1000000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("336d48ad5b7f2c72b98adc19cd7a56083f8e52f87958368810d47354b97acb38", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   1000000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("e4ab15e75aa66fb67b02a10bf2772269d1b6b135ebbd6480f29eef2fc8825934", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   1000000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("nhrZGEEh7JgVV3T1ncnUdTDZsByNnkmipc"),
+				Amount: 1712500, //含找零时的手续费是 787500 因此找零是 500000 这是个软灰尘
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+	}
+}
+
+func TestChangeBuilder_Build_DOGE_SoftDustChange(t *testing.T) {
+	netParams := dogecoin.TestNetParams
+
+	param := caseNewDogeChangeParam()
+	change := &ChangeTo{AddressX: MustNewAddress("nVnVaL5e4L2GDRha9aQ7KiSXDnqjUUz1K4", &netParams)}
+
+	// 狗狗币的软灰尘费和软灰尘限制相同，因此软灰尘的找零扣掉软灰尘费以后就不够了，只能并入手续费
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1500000, dogecoin.NewDogeDustFee(), dogecoin.NewDogeDustLimit()))
+	require.NoError(t, err)
+	require.False(t, res.HasChange())
+	require.Equal(t, btcutil.Amount(1287500), res.Fee)
+}
+
+func TestChangeBuilder_Build_SoftDustChangeExtraFee(t *testing.T) {
+	netParams := dogecoin.TestNetParams
+
+	param := caseNewDogeChangeParam()
+	change := &ChangeTo{AddressX: MustNewAddress("nVnVaL5e4L2GDRha9aQ7KiSXDnqjUUz1K4", &netParams)}
+
+	// 假设软灰尘费比较低，这时软灰尘的找零还是值得保留的，但需要额外交找零的软灰尘费
+	dustFee := dogecoin.NewDogeDustFee()
+	dustFee.ExtraDustFee = 100000
+
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1500000, dustFee, dogecoin.NewDogeDustLimit()))
+	require.NoError(t, err)
+	require.True(t, res.HasChange())
+	require.Equal(t, btcutil.Amount(787500+100000), res.Fee)
+	require.Equal(t, btcutil.Amount(400000), res.ChangeAmount)
+	require.Equal(t, res.Fee, res.TxParams.GetFee())
+}
+
+func TestChangeBuilder_Build_KeepFields(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(2)
+	param.OutList[0].Amount = 1234
+	param.Ordering = NewTxOrderingBIP69()
+	param.LockTime = 2800000
+	param.FeeGuard = &FeeGuard{MaxFee: 100000}
+
+	change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+	res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+	require.NoError(t, err)
+	require.True(t, res.HasChange())
+	//除了追加的找零输出，其它的字段都和原来的相同
+	expected := param.Clone()
+	expected.OutList = append(expected.OutList, res.TxParams.OutList[res.ChangeIndex])
+	require.Equal(t, expected, res.TxParams)
+	require.Len(t, param.OutList, 1)
+}
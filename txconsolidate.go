@@ -0,0 +1,132 @@
+package gobtcsign
+
+import (
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// MaxStandardTxVSize 标准交易的最大 v-size，节点默认不转发超过 400000 weight 的交易，即 100kvB
+const MaxStandardTxVSize = 400000 / blockchain.WitnessScaleFactor
+
+// ConsolidateParam 归集参数，交易所的充值钱包会积累大量的小额UTXO，需要在费率低时把它们合并成少量的大额UTXO
+type ConsolidateParam struct {
+	VinList       []VinType      //待归集的UTXO列表，会按照这个顺序分批
+	Target        AddressTuple   //归集的目标地址
+	FeeRatePerKb  btcutil.Amount //费率，单位是 聪/千字节，和 EstimateTxFee 的参数含义相同
+	MaxTxVSize    int            //单笔交易的最大 v-size，为0时使用标准交易的上限 MaxStandardTxVSize
+	MaxTotalFee   btcutil.Amount //全部归集交易的手续费预算，为0时表示不限制，超出预算的UTXO留到下次再归集
+	DustFee       DustFee        //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit     *DustLimit     //灰尘判定规则，为空时使用比特币的规则
+	RelayFeePerKb btcutil.Amount //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	RBFInfo       RBFConfig      //拼出来的交易的RBF配置
+}
+
+// ConsolidatePlan 归集计划
+type ConsolidatePlan struct {
+	TxList    []*BitcoinTxParams //归集交易列表，每个都可以直接签名
+	Skipped   []VinType          //在当前费率下有效价值<=0的UTXO，花费它们反而亏钱，因此跳过
+	Remaining []VinType          //超出手续费预算，或者凑不出非灰尘输出的UTXO，留到下次再归集
+	TotalFee  btcutil.Amount     //全部归集交易的手续费之和
+}
+
+// GetMaxTxVSize 获得单笔交易的最大 v-size
+func (param *ConsolidateParam) GetMaxTxVSize() int {
+	if param.MaxTxVSize > 0 {
+		return param.MaxTxVSize
+	}
+	return MaxStandardTxVSize
+}
+
+// PlanConsolidation 把UTXO分批，每批组成一个不超过 v-size 上限的归集交易，而且全部交易的手续费之和不超过预算
+func PlanConsolidation(param *ConsolidateParam, netParams *chaincfg.Params) (*ConsolidatePlan, error) {
+	targetScript, err := param.Target.GetPkScript(netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong target.address->pk-script")
+	}
+	outputs := []*wire.TxOut{wire.NewTxOut(0, targetScript)}
+
+	var plan = &ConsolidatePlan{}
+
+	var vinList = make([]VinType, 0, len(param.VinList))
+	var inputSizes = make([]int, 0, len(param.VinList))
+	for _, vin := range param.VinList {
+		pkScript, err := vin.Sender.GetPkScript(netParams)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong sender.address->pk-script")
+		}
+		inputSize, err := estimateInputVSize(pkScript)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-input-size")
+		}
+		//有效价值<=0的UTXO花了反而亏钱，就跳过它
+		if vin.Amount-feeForVSizeCeil(param.FeeRatePerKb, inputSize) <= 0 {
+			plan.Skipped = append(plan.Skipped, vin)
+			continue
+		}
+		vinList = append(vinList, vin)
+		inputSizes = append(inputSizes, inputSize)
+	}
+
+	baseSize, err := EstimateSize(nil, outputs, NewNoChange())
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong estimate-size")
+	}
+
+	var maxTxVSize = param.GetMaxTxVSize()
+	for start := 0; start < len(vinList); {
+		var budget btcutil.Amount = -1 //剩余的手续费预算，-1表示不限制
+		if param.MaxTotalFee > 0 {
+			budget = param.MaxTotalFee - plan.TotalFee
+		}
+		//尽量多地把UTXO放进同一笔交易，直到超过 v-size 上限或者剩余的手续费预算
+		//逐个累加输入的 v-size，输入个数的 var-int 变长时补上多出的字节，这样估出的大小不会小于实际大小
+		var end = start
+		var size = baseSize
+		for end < len(vinList) {
+			next := size + inputSizes[end] + wire.VarIntSerializeSize(uint64(end-start+1)) - wire.VarIntSerializeSize(uint64(end-start))
+			if next > maxTxVSize || (budget >= 0 && feeForVSizeCeil(param.FeeRatePerKb, next) > int64(budget)) {
+				break
+			}
+			size = next
+			end++
+		}
+		if end == start {
+			if size = baseSize + inputSizes[start]; size > maxTxVSize {
+				return nil, errors.Errorf("wrong max-tx-v-size=%d is less than one-input-tx-size=%d", maxTxVSize, size)
+			}
+			plan.Remaining = append(plan.Remaining, vinList[start:]...) //连一个输入的交易都超出手续费预算，剩下的都留到下次
+			break
+		}
+
+		txParams, err := BuildSweepTx(&SweepParam{
+			VinList:       vinList[start:end],
+			Target:        param.Target,
+			FeeRatePerKb:  param.FeeRatePerKb,
+			DustFee:       param.DustFee,
+			DustLimit:     param.DustLimit,
+			RelayFeePerKb: param.RelayFeePerKb,
+			RBFInfo:       param.RBFInfo,
+		}, netParams)
+		if err != nil {
+			var dustErr *SweepDustError
+			if errors.As(err, &dustErr) {
+				plan.Remaining = append(plan.Remaining, vinList[start:end]...) //凑不出非灰尘的输出，留到下次
+				start = end
+				continue
+			}
+			return nil, errors.WithMessage(err, "wrong build-sweep-tx")
+		}
+		if fee := txParams.GetFee(); budget >= 0 && fee > budget {
+			//估算的大小是上限，正常不会走到这里，保险起见仍然检查，超出预算时剩下的都留到下次
+			plan.Remaining = append(plan.Remaining, vinList[start:]...)
+			break
+		}
+		plan.TxList = append(plan.TxList, txParams)
+		plan.TotalFee += txParams.GetFee()
+		start = end
+	}
+	return plan, nil
+}
@@ -0,0 +1,160 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func caseNewConsolidateVinList(senderAddress string, amounts []int64) []VinType {
+	var vinList = make([]VinType, 0, len(amounts))
+	for idx, amount := range amounts {
+		vinList = append(vinList, VinType{
+			OutPoint: *wire.NewOutPoint(ptrHash(chainhash.HashH([]byte{byte(idx)})), uint32(idx)),
+			Sender:   *NewAddressTuple(senderAddress),
+			Amount:   amount,
+			RBFInfo:  *NewRBFNotUse(),
+		})
+	}
+	return vinList
+}
+
+func ptrHash(hash chainhash.Hash) *chainhash.Hash {
+	return &hash
+}
+
+func TestPlanConsolidation(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	var amounts []int64
+	for idx := 0; idx < 30; idx++ {
+		amounts = append(amounts, 5000+int64(idx)*100)
+	}
+	amounts = append(amounts, 50) //在这个费率下花费它是亏钱的
+
+	param := &ConsolidateParam{
+		VinList:      caseNewConsolidateVinList(senderAddress, amounts),
+		Target:       *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+		FeeRatePerKb: 2000,
+		MaxTxVSize:   800,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}
+
+	plan, err := PlanConsolidation(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, plan.Skipped, 1)
+	require.Equal(t, int64(50), plan.Skipped[0].Amount)
+	require.Empty(t, plan.Remaining)
+	require.Greater(t, len(plan.TxList), 1)
+
+	var vinCount int
+	var totalFee btcutil.Amount
+	for _, txParams := range plan.TxList {
+		size, err := txParams.EstimateTxSize(&netParams, NewNoChange())
+		require.NoError(t, err)
+		require.LessOrEqual(t, size, 800)
+
+		signParam, err := txParams.CreateTxSignParams(&netParams)
+		require.NoError(t, err)
+		require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+		require.NoError(t, txParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+		require.LessOrEqual(t, GetMsgTxVSize(signParam.MsgTx), 800)
+
+		vinCount += len(txParams.VinList)
+		totalFee += txParams.GetFee()
+	}
+	require.Equal(t, 30, vinCount)
+	require.Equal(t, totalFee, plan.TotalFee)
+	t.Log("tx-count:", len(plan.TxList), "total-fee:", plan.TotalFee)
+}
+
+func TestPlanConsolidation_FeeBudget(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	var amounts []int64
+	for idx := 0; idx < 30; idx++ {
+		amounts = append(amounts, 5000)
+	}
+
+	param := &ConsolidateParam{
+		VinList:      caseNewConsolidateVinList(senderAddress, amounts),
+		Target:       *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+		FeeRatePerKb: 2000,
+		MaxTxVSize:   800,
+		MaxTotalFee:  2000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}
+
+	plan, err := PlanConsolidation(param, &netParams)
+	require.NoError(t, err)
+	require.NotEmpty(t, plan.TxList)
+	require.NotEmpty(t, plan.Remaining)
+	require.LessOrEqual(t, plan.TotalFee, btcutil.Amount(2000))
+
+	var vinCount = len(plan.Remaining)
+	for _, txParams := range plan.TxList {
+		vinCount += len(txParams.VinList)
+	}
+	require.Equal(t, 30, vinCount)
+	t.Log("tx-count:", len(plan.TxList), "remaining:", len(plan.Remaining), "total-fee:", plan.TotalFee)
+
+	//预算不够一整批时，缩小这批的输入个数，而不是全部留到下次
+	fullPlan, err := PlanConsolidation(&ConsolidateParam{
+		VinList:      param.VinList,
+		Target:       param.Target,
+		FeeRatePerKb: param.FeeRatePerKb,
+		MaxTxVSize:   param.MaxTxVSize,
+		DustFee:      param.DustFee,
+		DustLimit:    param.DustLimit,
+		RBFInfo:      param.RBFInfo,
+	}, &netParams)
+	require.NoError(t, err)
+	fullBatchFee := fullPlan.TxList[0].GetFee()
+
+	param.MaxTotalFee = fullBatchFee / 2
+	plan, err = PlanConsolidation(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, plan.TxList, 1)
+	require.NotEmpty(t, plan.TxList[0].VinList)
+	require.Less(t, len(plan.TxList[0].VinList), len(fullPlan.TxList[0].VinList))
+	require.LessOrEqual(t, plan.TotalFee, param.MaxTotalFee)
+	require.Len(t, plan.Remaining, 30-len(plan.TxList[0].VinList))
+	t.Log("full-batch-fee:", fullBatchFee, "vin-count:", len(plan.TxList[0].VinList), "total-fee:", plan.TotalFee)
+
+	//连一个输入的交易都超出预算时，全部留到下次
+	param.MaxTotalFee = 100
+	plan, err = PlanConsolidation(param, &netParams)
+	require.NoError(t, err)
+	require.Empty(t, plan.TxList)
+	require.Len(t, plan.Remaining, 30)
+}
+
+func TestPlanConsolidation_MaxTxVSizeTooSmall(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &ConsolidateParam{
+		VinList:      caseNewConsolidateVinList(senderAddress, []int64{5000}),
+		Target:       *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+		FeeRatePerKb: 2000,
+		MaxTxVSize:   50,
+	}
+
+	_, err := PlanConsolidation(param, &netParams)
+	require.Error(t, err)
+	t.Log(err)
+}
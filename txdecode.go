@@ -0,0 +1,250 @@
+package gobtcsign
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// DecodedTx 交易的可读信息，字段名和 Bitcoin Core 的 decoderawtransaction 相同，数量的单位是币（BTC/DOGE）而不是聪
+// 提供前置输出的查询时，还会得到各个输入的前置输出和手续费，这和 getrawtransaction 的 verbosity=2 类似
+type DecodedTx struct {
+	Txid     string         `json:"txid"`
+	Hash     string         `json:"hash"` //即 wtxid，没有见证数据时和 txid 相同
+	Version  int32          `json:"version"`
+	Size     int            `json:"size"`
+	VSize    int            `json:"vsize"`
+	Weight   int            `json:"weight"`
+	LockTime uint32         `json:"locktime"`
+	Vin      []*DecodedVin  `json:"vin"`
+	Vout     []*DecodedVout `json:"vout"`
+	Fee      *DecodedAmount `json:"fee,omitempty"`     //手续费，只有提供前置输出的查询时才有，是负数时说明前置输出的数量不对
+	FeeRate  *float64       `json:"feerate,omitempty"` //费率，单位是 聪/字节（sat/vB），这是 Core 没有的字段，便于看板展示，手续费是负数时没有
+	Hex      string         `json:"hex,omitempty"`     //原始交易，只有从十六进制解码时才有
+}
+
+// DecodedAmount 数量，内部是聪，JSON 里按币输出为固定8位小数的数字，比如 0.00000010
+// float64 的数量在 JSON 里可能输出成 1e-07 这样的科学计数法，而且有舍入误差，因此不用它
+type DecodedAmount btcutil.Amount
+
+// MarshalJSON 按整数运算输出8位小数，不经过浮点数
+func (a DecodedAmount) MarshalJSON() ([]byte, error) {
+	var sign = ""
+	var value = int64(a)
+	if value < 0 {
+		sign = "-"
+		value = -value //聪的数量远小于 math.MinInt64，取反不会溢出
+	}
+	number := json.Number(fmt.Sprintf("%s%d.%08d", sign, value/btcutil.SatoshiPerBitcoin, value%btcutil.SatoshiPerBitcoin))
+	return json.Marshal(number)
+}
+
+// UnmarshalJSON 按十进制文本精确转换为聪，也接受科学计数法
+func (a *DecodedAmount) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	negative := strings.HasPrefix(text, "-")
+	amount, err := parseDecimalAmount(strings.TrimPrefix(text, "-"))
+	if err != nil {
+		return errors.WithMessage(err, "wrong decoded amount")
+	}
+	if negative {
+		amount = -amount
+	}
+	*a = DecodedAmount(amount)
+	return nil
+}
+
+// ToBTC 单位是币的数量
+func (a DecodedAmount) ToBTC() float64 {
+	return btcutil.Amount(a).ToBTC()
+}
+
+// DecodedVin 交易的输入，coinbase 交易的输入只有 coinbase 和 sequence 字段
+type DecodedVin struct {
+	Coinbase    string            `json:"coinbase,omitempty"`
+	Txid        string            `json:"txid,omitempty"`
+	Vout        *uint32           `json:"vout,omitempty"` //使用指针是因为0也是有效的位置
+	ScriptSig   *DecodedScriptSig `json:"scriptSig,omitempty"`
+	TxInWitness []string          `json:"txinwitness,omitempty"`
+	Prevout     *DecodedPrevout   `json:"prevout,omitempty"`
+	Sequence    uint32            `json:"sequence"`
+}
+
+// DecodedScriptSig 输入的解锁脚本
+type DecodedScriptSig struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
+}
+
+// DecodedPrevout 输入的前置输出
+type DecodedPrevout struct {
+	Value        DecodedAmount        `json:"value"`
+	ScriptPubKey *DecodedScriptPubKey `json:"scriptPubKey"`
+}
+
+// DecodedVout 交易的输出
+type DecodedVout struct {
+	Value        DecodedAmount        `json:"value"`
+	N            uint32               `json:"n"`
+	ScriptPubKey *DecodedScriptPubKey `json:"scriptPubKey"`
+}
+
+// DecodedScriptPubKey 输出的锁定脚本，类型名和 Core 相同，比如 "pubkeyhash"、"witness_v0_keyhash"、"nulldata"、"anchor"
+// 只有单一地址的脚本才有地址，P2PK 和裸多签没有地址
+type DecodedScriptPubKey struct {
+	Asm     string `json:"asm"`
+	Hex     string `json:"hex"`
+	Address string `json:"address,omitempty"`
+	Type    string `json:"type"`
+}
+
+// GetFee 手续费，单位是聪，没有提供前置输出的查询时返回 false
+func (tx *DecodedTx) GetFee() (btcutil.Amount, bool) {
+	if tx.Fee == nil {
+		return 0, false
+	}
+	return btcutil.Amount(*tx.Fee), true
+}
+
+// NewDecodedTxFromHex 解码十六进制的交易，preImp 允许为空，不为空时会查询前置输出，得到手续费和费率
+func NewDecodedTxFromHex(txHex string, netParams *chaincfg.Params, preImp GetUtxoFromInterface) (*DecodedTx, error) {
+	msgTx, err := NewMsgTxFromHex(txHex)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong new-msg-tx-from-hex")
+	}
+	decodedTx, err := NewDecodedTxFromMsgTx(msgTx, netParams, preImp)
+	if err != nil {
+		return nil, err
+	}
+	decodedTx.Hex = txHex
+	return decodedTx, nil
+}
+
+// NewDecodedTxFromMsgTx 得到交易的可读信息，preImp 允许为空，不为空时会查询前置输出，得到手续费和费率
+func NewDecodedTxFromMsgTx(msgTx *wire.MsgTx, netParams *chaincfg.Params, preImp GetUtxoFromInterface) (*DecodedTx, error) {
+	decodedTx := &DecodedTx{
+		Txid:     msgTx.TxHash().String(),
+		Hash:     msgTx.WitnessHash().String(),
+		Version:  msgTx.Version,
+		Size:     msgTx.SerializeSize(),
+		VSize:    GetMsgTxVSize(msgTx),
+		Weight:   3*msgTx.SerializeSizeStripped() + msgTx.SerializeSize(),
+		LockTime: msgTx.LockTime,
+		Vin:      make([]*DecodedVin, 0, len(msgTx.TxIn)),
+		Vout:     make([]*DecodedVout, 0, len(msgTx.TxOut)),
+	}
+
+	isCoinbase := blockchain.IsCoinBaseTx(msgTx)
+	var inputAmount int64
+	for _, txIn := range msgTx.TxIn {
+		vin := &DecodedVin{
+			TxInWitness: newDecodedWitness(txIn.Witness),
+			Sequence:    txIn.Sequence,
+		}
+		if isCoinbase {
+			vin.Coinbase = hex.EncodeToString(txIn.SignatureScript)
+			decodedTx.Vin = append(decodedTx.Vin, vin)
+			continue
+		}
+		vout := txIn.PreviousOutPoint.Index
+		vin.Txid = txIn.PreviousOutPoint.Hash.String()
+		vin.Vout = &vout
+		vin.ScriptSig = &DecodedScriptSig{
+			Asm: disasmScript(txIn.SignatureScript),
+			Hex: hex.EncodeToString(txIn.SignatureScript),
+		}
+		if preImp != nil {
+			utxoFrom, err := preImp.GetUtxoFrom(txIn.PreviousOutPoint)
+			if err != nil {
+				return nil, errors.WithMessage(err, "get-utxo-from")
+			}
+			pkScript, err := utxoFrom.sender.GetPkScript(netParams)
+			if err != nil {
+				return nil, errors.WithMessage(err, "wrong sender.address->pk-script")
+			}
+			vin.Prevout = &DecodedPrevout{
+				Value:        DecodedAmount(utxoFrom.amount),
+				ScriptPubKey: newDecodedScriptPubKey(pkScript, netParams),
+			}
+			inputAmount += utxoFrom.amount
+		}
+		decodedTx.Vin = append(decodedTx.Vin, vin)
+	}
+
+	var outputAmount int64
+	for idx, txOut := range msgTx.TxOut {
+		decodedTx.Vout = append(decodedTx.Vout, &DecodedVout{
+			Value:        DecodedAmount(txOut.Value),
+			N:            uint32(idx),
+			ScriptPubKey: newDecodedScriptPubKey(txOut.PkScript, netParams),
+		})
+		outputAmount += txOut.Value
+	}
+
+	//coinbase 交易没有前置输出，也就没有手续费
+	if preImp != nil && !isCoinbase {
+		fee := DecodedAmount(inputAmount - outputAmount)
+		decodedTx.Fee = &fee
+		//手续费是负数时，前置输出的查询结果是不对的，这时的费率没有意义
+		if fee >= 0 {
+			feeRate := float64(fee) / float64(decodedTx.VSize)
+			decodedTx.FeeRate = &feeRate
+		}
+	}
+	return decodedTx, nil
+}
+
+// newDecodedScriptPubKey 得到锁定脚本的类型和地址
+func newDecodedScriptPubKey(pkScript []byte, netParams *chaincfg.Params) *DecodedScriptPubKey {
+	res := &DecodedScriptPubKey{
+		Asm: disasmScript(pkScript),
+		Hex: hex.EncodeToString(pkScript),
+	}
+	if IsPayToAnchor(pkScript) { //btcd 不认识P2A，Core 把它的类型叫做 anchor
+		res.Type = "anchor"
+		if address, err := GetPayToAnchorAddress(netParams); err == nil {
+			res.Address = address
+		}
+		return res
+	}
+	address, scriptClass, _ := GetPkScriptAddress(pkScript, netParams) //没有地址时地址是空的，类型仍然是有效的
+	res.Address = address
+	res.Type = scriptClass.String()
+	return res
+}
+
+// newDecodedWitness 见证数据的每一项转换为十六进制
+func newDecodedWitness(witness wire.TxWitness) []string {
+	if len(witness) == 0 {
+		return nil
+	}
+	var items = make([]string, 0, len(witness))
+	for _, item := range witness {
+		items = append(items, hex.EncodeToString(item))
+	}
+	return items
+}
+
+// disasmScript 脚本的汇编文本，脚本解析失败时 DisasmString 也会返回解析成功的部分，这里不关心错误
+func disasmScript(script []byte) string {
+	asm, _ := txscript.DisasmString(script)
+	return asm
+}
+
+// encodeSegWitAddress 把见证程序编码为 bech32m 地址，btcutil 只支持 v0 和 taproot 地址，P2A 是两字节的 v1 见证程序
+func encodeSegWitAddress(hrp string, witnessVersion byte, witnessProgram []byte) (string, error) {
+	converted, err := bech32.ConvertBits(witnessProgram, 8, 5, true)
+	if err != nil {
+		return "", errors.WithMessage(err, "wrong convert-bits")
+	}
+	return bech32.EncodeM(hrp, append([]byte{witnessVersion}, converted...))
+}
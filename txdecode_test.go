@@ -0,0 +1,120 @@
+package gobtcsign
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDecodedTxFromHex(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(2)
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	txHex, err := CvtMsgTxToHex(signParam.MsgTx)
+	require.NoError(t, err)
+
+	//不查询前置输出时没有手续费
+	decodedTx, err := NewDecodedTxFromHex(txHex, &netParams, nil)
+	require.NoError(t, err)
+	require.Equal(t, signParam.MsgTx.TxHash().String(), decodedTx.Txid)
+	require.NotEqual(t, decodedTx.Txid, decodedTx.Hash)
+	require.Equal(t, GetMsgTxVSize(signParam.MsgTx), decodedTx.VSize)
+	require.Equal(t, int32(2), decodedTx.Version)
+	require.Len(t, decodedTx.Vin, 1)
+	require.Equal(t, uint32(2), *decodedTx.Vin[0].Vout)
+	require.Len(t, decodedTx.Vin[0].TxInWitness, 2)
+	require.Nil(t, decodedTx.Vin[0].Prevout)
+	require.Len(t, decodedTx.Vout, 1)
+	require.Equal(t, DecodedAmount(12000), decodedTx.Vout[0].Value)
+	require.Equal(t, 0.00012, decodedTx.Vout[0].Value.ToBTC())
+	require.Equal(t, "witness_v0_keyhash", decodedTx.Vout[0].ScriptPubKey.Type)
+	require.Equal(t, "tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx", decodedTx.Vout[0].ScriptPubKey.Address)
+	require.Nil(t, decodedTx.Fee)
+	_, ok := decodedTx.GetFee()
+	require.False(t, ok)
+
+	//查询前置输出时得到手续费和费率
+	preImp := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		param.VinList[0].OutPoint: NewSenderAmountUtxo(NewAddressTuple(senderAddress), 13089),
+	})
+	decodedTx, err = NewDecodedTxFromHex(txHex, &netParams, preImp)
+	require.NoError(t, err)
+	fee, ok := decodedTx.GetFee()
+	require.True(t, ok)
+	require.Equal(t, btcutil.Amount(1089), fee)
+	require.Equal(t, 0.00001089, decodedTx.Fee.ToBTC())
+	require.NotNil(t, decodedTx.FeeRate)
+	require.Equal(t, senderAddress, decodedTx.Vin[0].Prevout.ScriptPubKey.Address)
+
+	data, err := json.Marshal(decodedTx)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"fee":0.00001089,`)
+	require.Contains(t, string(data), `"value":0.00012000,`)
+	t.Log(string(data))
+
+	//前置输出的数量不对时手续费是负数，这时没有费率
+	preImp = NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		param.VinList[0].OutPoint: NewSenderAmountUtxo(NewAddressTuple(senderAddress), 11000),
+	})
+	decodedTx, err = NewDecodedTxFromHex(txHex, &netParams, preImp)
+	require.NoError(t, err)
+	fee, ok = decodedTx.GetFee()
+	require.True(t, ok)
+	require.Equal(t, btcutil.Amount(-1000), fee)
+	require.Nil(t, decodedTx.FeeRate)
+}
+
+func TestDecodedAmount_JSON(t *testing.T) {
+	for amount, text := range map[DecodedAmount]string{
+		0:                `0.00000000`,
+		10:               `0.00000010`,
+		12000:            `0.00012000`,
+		2099999997690000: `20999999.97690000`,
+		-1000:            `-0.00001000`,
+	} {
+		data, err := json.Marshal(amount)
+		require.NoError(t, err)
+		require.Equal(t, text, string(data))
+
+		var res DecodedAmount
+		require.NoError(t, json.Unmarshal(data, &res))
+		require.Equal(t, amount, res)
+	}
+
+	var res DecodedAmount
+	require.NoError(t, json.Unmarshal([]byte(`1e-07`), &res))
+	require.Equal(t, DecodedAmount(10), res)
+	require.Error(t, json.Unmarshal([]byte(`"abc"`), &res))
+}
+
+func TestNewDecodedTxFromMsgTx_Scripts(t *testing.T) {
+	netParams := chaincfg.MainNetParams
+
+	nullData, err := NewNullDataOutput([]byte("memo"))
+	require.NoError(t, err)
+	nullDataScript, err := nullData.GetPkScript(&netParams)
+	require.NoError(t, err)
+
+	msgTx := wire.NewMsgTx(3)
+	msgTx.AddTxIn(wire.NewTxIn(MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 0), nil, nil))
+	msgTx.AddTxOut(wire.NewTxOut(0, PayToAnchorScript))
+	msgTx.AddTxOut(wire.NewTxOut(0, nullDataScript))
+
+	decodedTx, err := NewDecodedTxFromMsgTx(msgTx, &netParams, nil)
+	require.NoError(t, err)
+	require.Equal(t, decodedTx.Txid, decodedTx.Hash)
+	require.Equal(t, "anchor", decodedTx.Vout[0].ScriptPubKey.Type)
+	require.Equal(t, "bc1pfeessrawgf", decodedTx.Vout[0].ScriptPubKey.Address)
+	require.Equal(t, "nulldata", decodedTx.Vout[1].ScriptPubKey.Type)
+	require.Empty(t, decodedTx.Vout[1].ScriptPubKey.Address)
+}
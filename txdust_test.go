@@ -110,3 +110,17 @@ func TestIsDustOutput_DOGE(t *testing.T) {
 		t.Log("amount:", amount, "IS NOT DUST IN DOGE")
 	}
 }
+
+func TestIsDustOutput_NullData(t *testing.T) {
+	output, err := NewNullDataOutput([]byte("deposit-tag:123456"))
+	require.NoError(t, err)
+	pkScript, err := output.GetPkScript(&chaincfg.MainNetParams)
+	require.NoError(t, err)
+
+	txOut := wire.NewTxOut(0, pkScript)
+	require.False(t, NewDustLimit().IsDustOutput(txOut, 1000))
+	require.False(t, dogecoin.NewDogeDustLimit().IsDustOutput(txOut, 0))
+
+	dustFee := dogecoin.NewDogeDustFee()
+	require.Equal(t, int64(0), dustFee.CountDustOutput([]*wire.TxOut{txOut}))
+}
@@ -27,7 +27,7 @@ func EstimateTxFee(param *BitcoinTxParams, netParams *chaincfg.Params, change *C
 	}
 	//有的链比如 DOGE_COIN 有软灰尘的概念，软灰尘需要消耗更高的手续费，而且这个手续费是不能协商的，而是必须交的，就得在这里交灰尘费
 	maxRequiredFee := txrules.FeeForSerializeSize(feeRatePerKb, maxSignedSize) + dustFee.SumExtraDustFee(outputs)
-	//但是请注意，input-output-maxFee 的结果还可能是个软灰尘，这时候就还得再增加找零的软灰尘费用，这个是后续逻辑需要考虑的
+	//但是请注意，input-output-maxFee 的结果还可能是个软灰尘，这时候就还得再增加找零的软灰尘费用，这个是后续逻辑需要考虑的，详见 ChangeBuilder 的逻辑
 	return maxRequiredFee, nil
 }
 
@@ -62,9 +62,12 @@ func EstimateSize(scripts [][]byte, outputs []*wire.TxOut, change *ChangeTo) (in
 
 	// We count the types of inputs, which we'll use to estimate
 	// the vsize of the transaction.
-	var nested, p2wpkh, p2tr, p2pkh int
+	var nested, p2wpkh, p2tr, p2pkh, p2a int
 	for _, pkScript := range scripts {
 		switch {
+		// P2A 输入不带签名，单独计算大小
+		case IsPayToAnchor(pkScript):
+			p2a++
 		// If this is a p2sh output, we assume this is a
 		// nested P2WKH.
 		case txscript.IsPayToScriptHash(pkScript):
@@ -83,6 +86,14 @@ func EstimateSize(scripts [][]byte, outputs []*wire.TxOut, change *ChangeTo) (in
 	maxSignedSize := txsizes.EstimateVirtualSize(
 		p2pkh, p2tr, p2wpkh, nested, outputs, changeScriptSize,
 	)
+	if p2a > 0 {
+		// P2A 输入的大小是固定的，另外输入个数的 varint 可能变长，当交易有见证时每个 P2A 输入还有1字节（1/4 v-byte）的空见证
+		maxSignedSize += p2a * payToAnchorInputSize
+		maxSignedSize += wire.VarIntSerializeSize(uint64(len(scripts))) - wire.VarIntSerializeSize(uint64(len(scripts)-p2a))
+		if nested+p2wpkh+p2tr > 0 {
+			maxSignedSize += (p2a + 3) / 4
+		}
+	}
 	return maxSignedSize, nil
 }
 
@@ -108,6 +119,21 @@ func (T *ChangeTo) GetChangeScriptSize() (int, error) {
 	return 0, nil //说明不需要找零输出，就返回0
 }
 
+// GetPkScript 获得找零输出的公钥脚本，优先使用找零脚本，其次使用找零地址，当两者皆为空时返回空表示不需要找零
+func (T *ChangeTo) GetPkScript() ([]byte, error) {
+	if T.PkScript != nil {
+		return T.PkScript, nil
+	}
+	if T.AddressX != nil {
+		pkScript, err := txscript.PayToAddrScript(T.AddressX)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong change_address")
+		}
+		return pkScript, nil
+	}
+	return nil, nil
+}
+
 // CalculateChangeAddressSize 根据钱包地址计算出找零输出的size
 func CalculateChangeAddressSize(address btcutil.Address) (int, error) {
 	pkScript, err := txscript.PayToAddrScript(address)
@@ -132,8 +158,10 @@ func CalculateChangePkScriptSize(pkScript []byte) (int, error) {
 		size = txsizes.P2WPKHPkScriptSize
 	case txscript.IsPayToTaproot(pkScript):
 		size = txsizes.P2TRPkScriptSize
+	case IsPayToAnchor(pkScript):
+		size = len(PayToAnchorScript)
 	default:
-		return 0, errors.New("UNSUPPORTED ADDRESS TYPE")
+		return 0, errors.WithStack(ErrUnsupportedAddressType)
 	}
 	return size, nil
 }
@@ -0,0 +1,144 @@
+package gobtcsign
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil/txsort"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// TxOrderMode 交易的输入和输出的排列方式
+type TxOrderMode int
+
+const (
+	TxOrderKeep    TxOrderMode = iota //保持调用方给的顺序，这是默认值，找零通常就在最后，会暴露哪个输出是找零
+	TxOrderBIP69                      //按 BIP69 字典序排列，输入按 txid（大端）和位置排，输出按数量和脚本排
+	TxOrderShuffle                    //随机排列输入和输出
+)
+
+// TxOrdering 交易的排列配置，在 CreateTxSignParams 拼交易时生效，VinList 和 OutList 本身不会被修改
+// 详见 https://github.com/bitcoin/bips/blob/master/bip-0069.mediawiki
+type TxOrdering struct {
+	Mode TxOrderMode
+	Rand *rand.Rand //随机排列时使用的随机数源，为空时使用当前时间作为种子，在单元测试里可以设置固定种子以便复现结果，注意它不是并发安全的
+}
+
+func NewTxOrderingKeep() TxOrdering {
+	return TxOrdering{Mode: TxOrderKeep}
+}
+
+func NewTxOrderingBIP69() TxOrdering {
+	return TxOrdering{Mode: TxOrderBIP69}
+}
+
+func NewTxOrderingShuffle(rnd *rand.Rand) TxOrdering {
+	return TxOrdering{Mode: TxOrderShuffle, Rand: rnd}
+}
+
+// IsKeep 是否保持调用方给的顺序
+func (T *TxOrdering) IsKeep() bool {
+	return T.Mode == TxOrderKeep
+}
+
+// apply 重新排列交易的输入和输出，待签名信息 inputOuts 跟着输入一起排列，以保证两者的位置序号相同
+// 返回排列前的每个输出在排列后的位置
+func (T *TxOrdering) apply(msgTx *wire.MsgTx, inputOuts []*wire.TxOut) ([]int, error) {
+	if len(msgTx.TxIn) != len(inputOuts) {
+		return nil, errors.Errorf("wrong input-outs count=%d tx-in count=%d", len(inputOuts), len(msgTx.TxIn))
+	}
+	var inPerm = newIdentityPerm(len(msgTx.TxIn))
+	var outPerm = newIdentityPerm(len(msgTx.TxOut))
+	switch T.Mode {
+	case TxOrderKeep:
+		return outPerm, nil
+	case TxOrderBIP69:
+		sort.SliceStable(inPerm, func(i, j int) bool {
+			return lessOutPointBIP69(msgTx.TxIn[inPerm[i]].PreviousOutPoint, msgTx.TxIn[inPerm[j]].PreviousOutPoint)
+		})
+		sort.SliceStable(outPerm, func(i, j int) bool {
+			a, b := msgTx.TxOut[outPerm[i]], msgTx.TxOut[outPerm[j]]
+			if a.Value != b.Value {
+				return a.Value < b.Value
+			}
+			return bytes.Compare(a.PkScript, b.PkScript) < 0
+		})
+	case TxOrderShuffle:
+		rnd := newSelectRand(T.Rand)
+		rnd.Shuffle(len(inPerm), func(i, j int) {
+			inPerm[i], inPerm[j] = inPerm[j], inPerm[i]
+		})
+		rnd.Shuffle(len(outPerm), func(i, j int) {
+			outPerm[i], outPerm[j] = outPerm[j], outPerm[i]
+		})
+	default:
+		return nil, errors.Errorf("wrong tx-order-mode=%d", T.Mode)
+	}
+
+	txIns := append([]*wire.TxIn{}, msgTx.TxIn...)
+	outs := append([]*wire.TxOut{}, inputOuts...)
+	for idx, pos := range inPerm {
+		msgTx.TxIn[idx] = txIns[pos]
+		inputOuts[idx] = outs[pos]
+	}
+	txOuts := append([]*wire.TxOut{}, msgTx.TxOut...)
+	var outIndexes = make([]int, len(outPerm))
+	for idx, pos := range outPerm {
+		msgTx.TxOut[idx] = txOuts[pos]
+		outIndexes[pos] = idx
+	}
+	return outIndexes, nil
+}
+
+// check 检查交易是否符合排列要求，只有 BIP69 是可以检查的
+func (T *TxOrdering) check(msgTx *wire.MsgTx) error {
+	if T.Mode == TxOrderBIP69 && !txsort.IsSorted(msgTx) {
+		return errors.WithMessage(ErrMsgTxMismatch, "tx is not bip69 sorted")
+	}
+	return nil
+}
+
+func newIdentityPerm(n int) []int {
+	var perm = make([]int, n)
+	for idx := 0; idx < n; idx++ {
+		perm[idx] = idx
+	}
+	return perm
+}
+
+// lessOutPointBIP69 按 BIP69 的规则比较输入，txid 按大端（也就是显示出来的十六进制）比较，相同时比较位置
+func lessOutPointBIP69(a, b wire.OutPoint) bool {
+	if a.Hash == b.Hash {
+		return a.Index < b.Index
+	}
+	for idx := chainhash.HashSize - 1; idx >= 0; idx-- {
+		if a.Hash[idx] != b.Hash[idx] {
+			return a.Hash[idx] < b.Hash[idx]
+		}
+	}
+	return false
+}
+
+// alignVinList 按交易里输入的顺序重新排列 VinList，通过 OutPoint 匹配，用于校验重新排列过的交易
+func (param *BitcoinTxParams) alignVinList(msgTx *wire.MsgTx) ([]VinType, error) {
+	if len(msgTx.TxIn) != len(param.VinList) {
+		return nil, errors.WithMessagef(ErrMsgTxMismatch, "input count mismatch: got %d, expected %d", len(msgTx.TxIn), len(param.VinList))
+	}
+	var vinMap = make(map[wire.OutPoint]VinType, len(param.VinList))
+	for _, input := range param.VinList {
+		vinMap[input.OutPoint] = input
+	}
+	var results = make([]VinType, 0, len(msgTx.TxIn))
+	for idx, txVin := range msgTx.TxIn {
+		input, ok := vinMap[txVin.PreviousOutPoint]
+		if !ok {
+			return nil, errors.WithMessagef(ErrMsgTxMismatch, "input %d outpoint mismatch: got %v, not in vin-list", idx, txVin.PreviousOutPoint)
+		}
+		delete(vinMap, txVin.PreviousOutPoint) //同一个UTXO不能出现两次
+		results = append(results, input)
+	}
+	return results, nil
+}
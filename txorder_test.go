@@ -0,0 +1,167 @@
+package gobtcsign
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/txsort"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+func caseNewOrderingTxParams(ordering TxOrdering) *BitcoinTxParams {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	return &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4900,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("5c98431bbb271ea3652168d2b4da8a76573fd8fec104e73f6f6f3a7c6fe6b97d", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4560,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("fcc889d7f0217694ab46d93f03a200d326c34e317552a6a33cb3fab03aa0b439", 1),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4320,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 5000,
+			},
+			{
+				Target: *NewAddressTuple(senderAddress), //找零
+				Amount: 1000,
+			},
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 3000,
+			},
+		},
+		RBFInfo:  *NewRBFActive(),
+		Ordering: ordering,
+	}
+}
+
+func TestTxOrdering_BIP69(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewOrderingTxParams(NewTxOrderingBIP69())
+
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.True(t, txsort.IsSorted(signParam.MsgTx))
+	require.Equal(t, int64(1000), signParam.MsgTx.TxOut[0].Value) //找零不再是最后一个
+
+	// 待签名信息需要跟着输入一起排列
+	for idx, txIn := range signParam.MsgTx.TxIn {
+		for _, vin := range param.VinList {
+			if vin.OutPoint == txIn.PreviousOutPoint {
+				require.Equal(t, vin.Amount, signParam.InputOuts[idx].Value)
+			}
+		}
+	}
+
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, param.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	// 交换两个输出以后就不是 BIP69 的顺序了
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.TxOut[0], msgTx.TxOut[1] = msgTx.TxOut[1], msgTx.TxOut[0]
+	require.ErrorIs(t, param.CheckMsgTxParam(msgTx, &netParams), ErrMsgTxMismatch)
+}
+
+func TestTxOrdering_Shuffle(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewOrderingTxParams(NewTxOrderingShuffle(rand.New(rand.NewSource(1))))
+
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, param.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	// 相同的种子得到相同的排列
+	same, err := caseNewOrderingTxParams(NewTxOrderingShuffle(rand.New(rand.NewSource(1)))).CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Equal(t, GetTxHash(same.MsgTx), GetTxHash(signParam.MsgTx)) //隔离见证的交易哈希和签名无关
+
+	// 篡改输出数量以后检查不通过
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.TxOut[0].Value++
+	require.ErrorIs(t, param.CheckMsgTxParam(msgTx, &netParams), ErrMsgTxMismatch)
+}
+
+func TestTxOrdering_Keep(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewOrderingTxParams(NewTxOrderingKeep())
+
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	for idx, txIn := range signParam.MsgTx.TxIn {
+		require.Equal(t, param.VinList[idx].OutPoint, txIn.PreviousOutPoint)
+	}
+	for idx, txOut := range signParam.MsgTx.TxOut {
+		require.Equal(t, param.OutList[idx].Amount, txOut.Value)
+	}
+	require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+	// 保持顺序时，交换输出就检查不通过
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.TxOut[0], msgTx.TxOut[1] = msgTx.TxOut[1], msgTx.TxOut[0]
+	require.ErrorIs(t, param.CheckMsgTxParam(msgTx, &netParams), ErrAddressPkScriptMismatch)
+}
+
+func TestSignParam_GetTxOutIndex(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	for _, ordering := range []TxOrdering{
+		NewTxOrderingKeep(),
+		NewTxOrderingBIP69(),
+		NewTxOrderingShuffle(rand.New(rand.NewSource(1))),
+	} {
+		param := caseNewOrderingTxParams(ordering)
+		param.OutList = param.OutList[:1] //去掉手写的找零，由 ChangeBuilder 计算
+
+		change := &ChangeTo{AddressX: MustNewAddress(senderAddress, &netParams)}
+		res, err := param.BuildWithChange(&netParams, NewChangeBuilder(change, 1000, NewDustFee(), NewDustLimit()))
+		require.NoError(t, err)
+		require.True(t, res.HasChange())
+		require.Equal(t, len(res.TxParams.OutList)-1, res.ChangeIndex) //这里是 OutList 里的位置
+
+		signParam, err := res.TxParams.CreateTxSignParams(&netParams)
+		require.NoError(t, err)
+		require.Len(t, signParam.TxOutIndexes, len(res.TxParams.OutList))
+		for idx, output := range res.TxParams.OutList {
+			require.Equal(t, output.Amount, signParam.MsgTx.TxOut[signParam.GetTxOutIndex(idx)].Value)
+		}
+		require.Equal(t, int64(res.ChangeAmount), signParam.MsgTx.TxOut[signParam.GetTxOutIndex(res.ChangeIndex)].Value)
+		require.Equal(t, -1, signParam.GetTxOutIndex(-1))
+	}
+
+	//BIP69 按数量从小到大排列，找零比转账少，排列以后在前面
+	param := caseNewOrderingTxParams(NewTxOrderingBIP69())
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 0, 1}, signParam.TxOutIndexes)
+}
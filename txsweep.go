@@ -0,0 +1,116 @@
+package gobtcsign
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/pkg/errors"
+)
+
+// SweepParam 清扫参数，把一批UTXO全部转到同一个地址，通常用于把充值地址的钱归集到热钱包
+type SweepParam struct {
+	VinList       []VinType      //需要清空的UTXO列表，可以使用 NewSweepVinList 通过前置输出查询得到
+	Target        AddressTuple   //接收全部资金的地址
+	FeeRatePerKb  btcutil.Amount //费率，单位是 聪/千字节，和 EstimateTxFee 的参数含义相同
+	DustFee       DustFee        //软灰尘的额外费用，比特币是空的，狗狗币需要使用 dogecoin.NewDogeDustFee()
+	DustLimit     *DustLimit     //灰尘判定规则，为空时使用比特币的规则
+	RelayFeePerKb btcutil.Amount //判定灰尘时使用的中继费率，为0时使用默认的 txrules.DefaultRelayFeePerKb
+	RBFInfo       RBFConfig      //拼出来的交易的RBF配置
+}
+
+// SweepDustError 清扫后的输出是灰尘（或者连手续费都不够）时返回这个错误，调用方可以使用 errors.As 判断，等UTXO更多或者费率更低时再清扫
+type SweepDustError struct {
+	InputAmount btcutil.Amount //全部输入的数量
+	Fee         btcutil.Amount //需要的手续费
+	Amount      btcutil.Amount //扣掉手续费以后的输出数量，可能是负数
+}
+
+func (e *SweepDustError) Error() string {
+	return fmt.Sprintf("sweep-output-is-dust input=%d fee=%d amount=%d", e.InputAmount, e.Fee, e.Amount)
+}
+
+func (e *SweepDustError) Is(target error) bool {
+	return target == ErrDustOutput
+}
+
+// NewSweepVinList 根据UTXO的位置信息查询前置输出，得到清扫交易的输入列表
+func NewSweepVinList(outPoints []wire.OutPoint, preImp GetUtxoFromInterface, rbfInfo RBFConfig) ([]VinType, error) {
+	var vinList = make([]VinType, 0, len(outPoints))
+	for _, outPoint := range outPoints {
+		utxoFrom, err := preImp.GetUtxoFrom(outPoint)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get-utxo-from")
+		}
+		vinList = append(vinList, VinType{
+			OutPoint: outPoint,
+			Sender:   *utxoFrom.sender,
+			Amount:   utxoFrom.amount,
+			RBFInfo:  rbfInfo,
+		})
+	}
+	return vinList, nil
+}
+
+// BuildSweepTx 拼出只有一个输出的清扫交易，输出数量就是全部输入减去 EstimateTxFee 计算出的手续费
+// 当输出是灰尘时返回 SweepDustError 错误
+func BuildSweepTx(param *SweepParam, netParams *chaincfg.Params) (*BitcoinTxParams, error) {
+	if len(param.VinList) == 0 {
+		return nil, errors.New("wrong sweep vin-list is empty")
+	}
+	pkScript, err := param.Target.GetPkScript(netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong target.address->pk-script")
+	}
+
+	var inputAmount btcutil.Amount
+	for _, vin := range param.VinList {
+		inputAmount += btcutil.Amount(vin.Amount)
+	}
+
+	txParams := &BitcoinTxParams{
+		VinList: append([]VinType{}, param.VinList...),
+		OutList: []OutType{{
+			Target: param.Target,
+			Amount: int64(inputAmount), //先假设全部转出，以计算手续费
+		}},
+		RBFInfo: param.RBFInfo,
+	}
+	//交易的大小和输出数量无关，但狗狗币的软灰尘费和输出数量有关，因此在扣掉手续费以后需要再算一遍
+	var fee btcutil.Amount
+	for idx := 0; idx < 2; idx++ {
+		fee, err = EstimateTxFee(txParams, netParams, NewNoChange(), param.FeeRatePerKb, param.DustFee)
+		if err != nil {
+			return nil, errors.WithMessage(err, "wrong estimate-tx-fee")
+		}
+		txParams.OutList[0].Amount = int64(inputAmount - fee)
+	}
+
+	amount := inputAmount - fee
+	if amount <= 0 || param.GetDustLimit().IsDustOutput(wire.NewTxOut(int64(amount), pkScript), param.GetRelayFeePerKb()) {
+		return nil, &SweepDustError{
+			InputAmount: inputAmount,
+			Fee:         fee,
+			Amount:      amount,
+		}
+	}
+	return txParams, nil
+}
+
+// GetDustLimit 获得灰尘判定规则，当没有设置时使用比特币的规则
+func (param *SweepParam) GetDustLimit() *DustLimit {
+	if param.DustLimit != nil {
+		return param.DustLimit
+	}
+	return NewDustLimit()
+}
+
+// GetRelayFeePerKb 获得判定灰尘时使用的中继费率
+func (param *SweepParam) GetRelayFeePerKb() btcutil.Amount {
+	if param.RelayFeePerKb > 0 {
+		return param.RelayFeePerKb
+	}
+	return txrules.DefaultRelayFeePerKb
+}
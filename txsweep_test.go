@@ -0,0 +1,126 @@
+package gobtcsign
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func TestBuildSweepTx(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	preMap := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{
+		*MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0): NewSenderAmountUtxo(NewAddressTuple(senderAddress), 4900),
+		*MustNewOutPoint("fcc889d7f0217694ab46d93f03a200d326c34e317552a6a33cb3fab03aa0b439", 1): NewSenderAmountUtxo(NewAddressTuple(senderAddress), 4320),
+		*MustNewOutPoint("5c98431bbb271ea3652168d2b4da8a76573fd8fec104e73f6f6f3a7c6fe6b97d", 0): NewSenderAmountUtxo(NewAddressTuple(senderAddress), 4560),
+	})
+
+	vinList, err := NewSweepVinList([]wire.OutPoint{
+		*MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+		*MustNewOutPoint("fcc889d7f0217694ab46d93f03a200d326c34e317552a6a33cb3fab03aa0b439", 1),
+		*MustNewOutPoint("5c98431bbb271ea3652168d2b4da8a76573fd8fec104e73f6f6f3a7c6fe6b97d", 0),
+	}, preMap, *NewRBFActive())
+	require.NoError(t, err)
+	require.Len(t, vinList, 3)
+
+	param := &SweepParam{
+		VinList:      vinList,
+		Target:       *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+		FeeRatePerKb: 2000,
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}
+
+	txParams, err := BuildSweepTx(param, &netParams)
+	require.NoError(t, err)
+	require.Len(t, txParams.OutList, 1)
+
+	fee, err := txParams.EstimateTxFee(&netParams, NewNoChange(), 2000, NewDustFee())
+	require.NoError(t, err)
+	require.Equal(t, fee, txParams.GetFee())
+	require.Equal(t, int64(4900+4320+4560)-int64(fee), txParams.OutList[0].Amount)
+	t.Log("fee:", fee, "amount:", txParams.OutList[0].Amount)
+
+	signParam, err := txParams.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	require.NoError(t, txParams.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+	require.NoError(t, txParams.CheckMsgTxParam(signParam.MsgTx, &netParams))
+	t.Log("estimate-size:", GetMsgTxVSize(signParam.MsgTx))
+}
+
+func TestBuildSweepTx_Dust(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := &SweepParam{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   4900,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		Target:       *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+		FeeRatePerKb: 44000, //费率很高，扣完手续费以后就是灰尘
+		DustFee:      NewDustFee(),
+		DustLimit:    NewDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}
+
+	_, err := BuildSweepTx(param, &netParams)
+	require.Error(t, err)
+	t.Log(err)
+
+	var dustErr *SweepDustError
+	require.True(t, errors.As(err, &dustErr))
+	require.Equal(t, btcutil.Amount(4900), dustErr.InputAmount)
+	require.Equal(t, dustErr.InputAmount-dustErr.Fee, dustErr.Amount)
+}
+
+func TestBuildSweepTx_DOGE(t *testing.T) {
+	const senderAddress = "nVnVaL5e4L2GDRha9aQ7KiSXDnqjUUz1K4"
+
+	netParams := dogecoin.TestNetParams
+
+	param := &SweepParam{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("5ae74f2d6c4a0513e3c75484a726820c2b0653c2b26352afe97f4bf813dcf859", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   1000000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+			{
+				OutPoint: *MustNewOutPoint("336d48ad5b7f2c72b98adc19cd7a56083f8e52f87958368810d47354b97acb38", 0),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   1000000,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		Target:       *NewAddressTuple("nhrZGEEh7JgVV3T1ncnUdTDZsByNnkmipc"),
+		FeeRatePerKb: 1500000,
+		DustFee:      dogecoin.NewDogeDustFee(),
+		DustLimit:    dogecoin.NewDogeDustLimit(),
+		RBFInfo:      *NewRBFActive(),
+	}
+
+	txParams, err := BuildSweepTx(param, &netParams)
+	require.NoError(t, err)
+
+	fee, err := txParams.EstimateTxFee(&netParams, NewNoChange(), 1500000, dogecoin.NewDogeDustFee())
+	require.NoError(t, err)
+	require.Equal(t, fee, txParams.GetFee())
+	t.Log("fee:", fee, "amount:", txParams.OutList[0].Amount)
+}
@@ -0,0 +1,68 @@
+package gobtcsign
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/pkg/errors"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+// TrucTxVersion 是 BIP431 的 TRUC（Topologically Restricted Until Confirmation）交易版本
+// 这种交易在确认之前只能组成"一个父交易+一个子交易"的拓扑，因此替换和CPFP的代价是可预期的，不容易被钉住（pinning）
+// 详见 https://github.com/bitcoin/bips/blob/master/bip-0431.mediawiki
+const TrucTxVersion = 3
+
+const (
+	TrucMaxVSize      = 10000 //TRUC 交易的最大 v-size
+	TrucChildMaxVSize = 1000  //花费未确认 TRUC 交易的子交易的最大 v-size
+)
+
+// IsTruc 是不是 TRUC 交易
+func (param *BitcoinTxParams) IsTruc() bool {
+	return param.GetTxVersion() == TrucTxVersion
+}
+
+// checkTxVersion 检查交易版本，版本3时再检查 TRUC 的规则
+func (param *BitcoinTxParams) checkTxVersion(netParams *chaincfg.Params) error {
+	switch param.Version {
+	case 0, 2: //自动选择时相对时间锁会使用版本2
+	case 1:
+		if param.HasRelativeLock() {
+			return errors.New("wrong tx version=1, relative-lock need version>=2")
+		}
+	case TrucTxVersion:
+		if dogecoin.IsDogeNet(netParams) {
+			return errors.Errorf("wrong tx version=%d, net=%s not support truc", param.Version, netParams.Name)
+		}
+		return param.checkTrucRules(netParams)
+	default:
+		return errors.Errorf("wrong tx version=%d", param.Version)
+	}
+	return nil
+}
+
+// checkTrucRules 检查在构建交易时就能检查的 TRUC 规则
+// 交易的 v-size 不能超过 TrucMaxVSize，未确认的输入只能来自同一个父交易，这时交易的 v-size 不能超过 TrucChildMaxVSize
+// 父交易也需要是 TRUC 交易，而且父交易在内存池里只能有一个子交易，这两条需要调用方根据链上的信息保证
+func (param *BitcoinTxParams) checkTrucRules(netParams *chaincfg.Params) error {
+	vSize, err := EstimateTxSize(param, netParams, NewNoChange())
+	if err != nil {
+		return errors.WithMessage(err, "wrong estimate-tx-size")
+	}
+	if vSize > TrucMaxVSize {
+		return errors.Errorf("wrong truc tx v-size=%d > max=%d", vSize, TrucMaxVSize)
+	}
+	var parents = make(map[chainhash.Hash]bool)
+	for _, input := range param.VinList {
+		if input.Unconfirmed {
+			parents[input.OutPoint.Hash] = true
+		}
+	}
+	if len(parents) > 1 {
+		return errors.Errorf("wrong truc tx unconfirmed-parent count=%d > 1", len(parents))
+	}
+	if len(parents) == 1 && vSize > TrucChildMaxVSize {
+		return errors.Errorf("wrong truc child tx v-size=%d > max=%d", vSize, TrucChildMaxVSize)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package gobtcsign
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+	"github.com/yyle88/gobtcsign/dogecoin"
+)
+
+func caseNewTxVersionParam(version int32) *BitcoinTxParams {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	return &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 2),
+				Sender:   *NewAddressTuple(senderAddress),
+				Amount:   13089,
+				RBFInfo:  *NewRBFNotUse(),
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("tb1qk0z8zhsq5hlewplv0039smnz62r2ujscz6gqjx"),
+				Amount: 12000,
+			},
+		},
+		RBFInfo: *NewRBFActive(),
+		Version: version,
+	}
+}
+
+func TestBitcoinTxParams_Version(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	for _, version := range []int32{0, 1, 2, 3} {
+		param := caseNewTxVersionParam(version)
+
+		signParam, err := param.CreateTxSignParams(&netParams)
+		require.NoError(t, err)
+		require.Equal(t, param.GetTxVersion(), signParam.MsgTx.Version)
+		require.Equal(t, version == 3, param.IsTruc())
+
+		require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+		require.NoError(t, param.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+		require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+
+		//从交易里还原的参数也有相同的版本
+		utxo := NewSenderAmountUtxo(&param.VinList[0].Sender, param.VinList[0].Amount)
+		preImp := NewSenderAmountUtxoCache(map[wire.OutPoint]*SenderAmountUtxo{param.VinList[0].OutPoint: utxo})
+		newParam, err := NewCustomParamFromMsgTx(signParam.MsgTx, preImp)
+		require.NoError(t, err)
+		require.Equal(t, signParam.MsgTx.Version, newParam.Version)
+	}
+	require.Equal(t, int32(1), caseNewTxVersionParam(0).GetTxVersion())
+}
+
+func TestBitcoinTxParams_Version_Wrong(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	_, err := caseNewTxVersionParam(4).CreateTxSignParams(&netParams)
+	require.Error(t, err)
+
+	param := caseNewTxVersionParam(1)
+	param.VinList[0].RelativeLock = NewRelativeLockBlocks(6)
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err)
+
+	//狗狗币没有 TRUC 交易
+	dogeParam := &BitcoinTxParams{
+		VinList: []VinType{
+			{
+				OutPoint: *MustNewOutPoint("173d5e1b33bb8a3d5c2c4ad3b2ff0d8e9cde10b8fb5d1a1dbdb3c3d0d8f8e0a1", 0),
+				Sender:   *NewAddressTuple("nkgVWbNrUowCG4mkWSzA7HHUDe3XyL2NaC"),
+				Amount:   100000000,
+			},
+		},
+		OutList: []OutType{
+			{
+				Target: *NewAddressTuple("ng4P16anXNUrQw6VKHmoMW8NHsTkFBdNrn"),
+				Amount: 90000000,
+			},
+		},
+		Version: 3,
+	}
+	_, err = dogeParam.CreateTxSignParams(&dogecoin.TestNetParams)
+	require.Error(t, err)
+	dogeParam.Version = 2
+	_, err = dogeParam.CreateTxSignParams(&dogecoin.TestNetParams)
+	require.NoError(t, err)
+}
+
+func TestBitcoinTxParams_Version_CheckMsgTxParam(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(3)
+	signParam, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+
+	param.Version = 2
+	require.ErrorIs(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams), ErrMsgTxMismatch)
+
+	//参数没有设置版本和锁定时间时不比较它们，比如检查外部拼出的版本2的交易
+	param.Version = 0
+	require.NoError(t, param.CheckMsgTxParam(signParam.MsgTx, &netParams))
+	msgTx := signParam.MsgTx.Copy()
+	msgTx.Version = 2
+	msgTx.LockTime = 800000
+	require.NoError(t, param.CheckMsgTxParam(msgTx, &netParams))
+}
+
+func TestBitcoinTxParams_Truc_UnconfirmedParents(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(3)
+	param.VinList[0].Unconfirmed = true
+	param.VinList = append(param.VinList, VinType{
+		OutPoint:    *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", 1),
+		Sender:      *NewAddressTuple(senderAddress),
+		Amount:      1000,
+		Unconfirmed: true,
+	})
+	_, err := param.CreateTxSignParams(&netParams)
+	require.NoError(t, err) //两个未确认的输入来自同一个父交易
+
+	param.VinList = append(param.VinList, VinType{
+		OutPoint:    *MustNewOutPoint("fb87cc4010bd4a34cb4be86f37182fada63c9923ae8eae5d2f793cb5f50c6328", 0),
+		Sender:      *NewAddressTuple(senderAddress),
+		Amount:      4900,
+		Unconfirmed: true,
+	})
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err) //未确认的输入来自两个父交易
+
+	param.VinList[2].Unconfirmed = false
+	_, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+}
+
+func TestBitcoinTxParams_Truc_VSize(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+
+	netParams := chaincfg.TestNet3Params
+
+	param := caseNewTxVersionParam(3)
+	for idx := 0; idx < 40; idx++ {
+		param.OutList = append(param.OutList, OutType{Target: *NewAddressTuple(senderAddress), Amount: 1000})
+	}
+	param.VinList[0].Amount += 40 * 1000
+	vSize, err := EstimateTxSize(param, &netParams, NewNoChange())
+	require.NoError(t, err)
+	require.Greater(t, vSize, TrucChildMaxVSize)
+	require.Less(t, vSize, TrucMaxVSize)
+
+	_, err = param.CreateTxSignParams(&netParams)
+	require.NoError(t, err)
+
+	//花费未确认的父交易时，子交易的大小限制更严格
+	param.VinList[0].Unconfirmed = true
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err)
+
+	for idx := 0; idx < 300; idx++ {
+		param.OutList = append(param.OutList, OutType{Target: *NewAddressTuple(senderAddress), Amount: 1000})
+	}
+	param.VinList[0].Amount += 300 * 1000
+	param.VinList[0].Unconfirmed = false
+	_, err = param.CreateTxSignParams(&netParams)
+	require.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"math"
+	"strings"
 
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/btcutil"
@@ -50,6 +51,10 @@ func NewMsgTxFromHex(txHex string) (*wire.MsgTx, error) {
 // GetAddressPkScript generates the corresponding public key script (PkScript) from the address string.
 // GetAddressPkScript 根据地址字符串生成对应的公钥脚本（PkScript），地址和公钥脚本是一对一的
 func GetAddressPkScript(addressString string, netParams *chaincfg.Params) ([]byte, error) {
+	//btcutil 解码不了P2A的地址，这里单独识别
+	if address, err := GetPayToAnchorAddress(netParams); err == nil && strings.EqualFold(addressString, address) {
+		return append([]byte{}, PayToAnchorScript...), nil
+	}
 	address, err := btcutil.DecodeAddress(addressString, netParams)
 	if err != nil {
 		return nil, errors.WithMessage(err, "wrong decode-address")
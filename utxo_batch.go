@@ -0,0 +1,133 @@
+package gobtcsign
+
+import (
+	"context"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// DefaultUtxoFetchParallelism 批量获取前置输出时默认的并发数，太大时节点可能会限流
+const DefaultUtxoFetchParallelism = 8
+
+// GetUtxoFromBatchInterface 批量获取前置输出，和 GetUtxoFromInterface 不同，它可以通过 ctx 取消，而且能并发地请求
+// 返回的 map 里包含全部请求的 outpoint，有任何一个获取失败时返回错误
+type GetUtxoFromBatchInterface interface {
+	GetUtxosFrom(ctx context.Context, utxos []wire.OutPoint) (map[wire.OutPoint]*SenderAmountUtxo, error)
+}
+
+// GetParallelism 获得批量请求时的并发数
+func (uc *SenderAmountUtxoClient) GetParallelism() int {
+	if uc.Parallelism > 0 {
+		return uc.Parallelism
+	}
+	return DefaultUtxoFetchParallelism
+}
+
+// GetUtxosFrom 批量获取前置输出，同一个交易的多个输出只请求一次 getrawtransaction，不同的交易并发请求
+// rpcclient 的请求本身不支持 ctx，因此 ctx 取消以后不会再发起新的请求，已经发起的请求会等它返回
+func (uc *SenderAmountUtxoClient) GetUtxosFrom(ctx context.Context, utxos []wire.OutPoint) (map[wire.OutPoint]*SenderAmountUtxo, error) {
+	return fetchUtxosByTxid(ctx, utxos, uc.GetParallelism(), func(ctx context.Context, txHash chainhash.Hash) ([]rawTxVout, error) {
+		vouts, err := getRawTransactionVouts(uc.client, txHash)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get-raw-transaction")
+		}
+		return vouts, nil
+	})
+}
+
+// GetUtxosFrom 批量获取前置输出，缓存里没有并发的必要，逐个查询即可
+func (uc SenderAmountUtxoCache) GetUtxosFrom(ctx context.Context, utxos []wire.OutPoint) (map[wire.OutPoint]*SenderAmountUtxo, error) {
+	var utxoMap = make(map[wire.OutPoint]*SenderAmountUtxo, len(utxos))
+	for _, utxo := range utxos {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		utxoFrom, err := uc.GetUtxoFrom(utxo)
+		if err != nil {
+			return nil, err
+		}
+		utxoMap[utxo] = utxoFrom
+	}
+	return utxoMap, nil
+}
+
+// fetchUtxosByTxid 按交易哈希去重以后并发获取交易的输出，再按 outpoint 取出并解析各个前置输出，没有请求的输出不会解析
+// 遇到第一个错误时取消其它还没开始的请求，并返回这个错误
+func fetchUtxosByTxid(
+	ctx context.Context,
+	utxos []wire.OutPoint,
+	parallelism int,
+	fetchTxOuts func(ctx context.Context, txHash chainhash.Hash) ([]rawTxVout, error),
+) (map[wire.OutPoint]*SenderAmountUtxo, error) {
+	var txHashes []chainhash.Hash
+	var txOutsMap = make(map[chainhash.Hash][]rawTxVout)
+	for _, utxo := range utxos {
+		if _, ok := txOutsMap[utxo.Hash]; !ok {
+			txOutsMap[utxo.Hash] = nil
+			txHashes = append(txHashes, utxo.Hash)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	var wg sync.WaitGroup
+	var sem = make(chan struct{}, max(parallelism, 1))
+	for _, txHash := range txHashes {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(txHash chainhash.Hash) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			txOuts, err := fetchTxOuts(ctx, txHash)
+			if err != nil {
+				setErr(errors.WithMessagef(err, "wrong fetch tx=%s", txHash.String()))
+				return
+			}
+			mutex.Lock()
+			txOutsMap[txHash] = txOuts
+			mutex.Unlock()
+		}(txHash)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var utxoMap = make(map[wire.OutPoint]*SenderAmountUtxo, len(utxos))
+	for _, utxo := range utxos {
+		utxoFrom, err := pickSenderAmountUtxo(txOutsMap[utxo.Hash], utxo)
+		if err != nil {
+			return nil, err
+		}
+		utxoMap[utxo] = utxoFrom
+	}
+	return utxoMap, nil
+}
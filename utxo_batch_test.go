@@ -0,0 +1,114 @@
+package gobtcsign
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// caseNewRawTxVout 节点返回的一个输出
+func caseNewRawTxVout(n uint32, value string, pkScriptHex string) rawTxVout {
+	vout := rawTxVout{Value: json.Number(value), N: n}
+	vout.ScriptPubKey.Hex = pkScriptHex
+	return vout
+}
+
+func TestGetUtxoFromBatchInterface(t *testing.T) {
+	var _ GetUtxoFromBatchInterface = &SenderAmountUtxoClient{}
+	var _ GetUtxoFromBatchInterface = &SenderAmountUtxoCache{}
+}
+
+func TestFetchUtxosByTxid(t *testing.T) {
+	const senderPkScriptHex = "001462152b40d8b2cbac358541d850c079ea10d1407f" //tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap
+
+	var utxos []wire.OutPoint
+	for idx := 0; idx < 20; idx++ {
+		txHash := chainhash.HashH([]byte{byte(idx)})
+		utxos = append(utxos, *wire.NewOutPoint(&txHash, 0), *wire.NewOutPoint(&txHash, 1)) //每个交易两个输出
+	}
+
+	var callCount, running, maxRunning int32
+	utxoMap, err := fetchUtxosByTxid(context.Background(), utxos, 3, func(ctx context.Context, txHash chainhash.Hash) ([]rawTxVout, error) {
+		atomic.AddInt32(&callCount, 1)
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&maxRunning)
+			if current <= old || atomic.CompareAndSwapInt32(&maxRunning, old, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return []rawTxVout{
+			caseNewRawTxVout(0, "0.00001", senderPkScriptHex),
+			caseNewRawTxVout(1, "2e-05", senderPkScriptHex),
+		}, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, utxoMap, 40)
+	require.Equal(t, int32(20), callCount) //同一个交易只请求一次
+	require.LessOrEqual(t, maxRunning, int32(3))
+	require.Equal(t, int64(2000), utxoMap[utxos[1]].amount)
+}
+
+func TestFetchUtxosByTxid_Wrong(t *testing.T) {
+	txHash := chainhash.HashH([]byte("tx"))
+	utxos := []wire.OutPoint{*wire.NewOutPoint(&txHash, 5)}
+
+	//输出的位置超出范围
+	_, err := fetchUtxosByTxid(context.Background(), utxos, 2, func(ctx context.Context, txHash chainhash.Hash) ([]rawTxVout, error) {
+		return []rawTxVout{caseNewRawTxVout(0, "0.00001", "51")}, nil
+	})
+	require.ErrorIs(t, err, ErrOutputIndex)
+	t.Log(err)
+
+	//请求失败
+	_, err = fetchUtxosByTxid(context.Background(), utxos, 2, func(ctx context.Context, txHash chainhash.Hash) ([]rawTxVout, error) {
+		return nil, errors.New("node is down")
+	})
+	require.Error(t, err)
+
+	//已经取消时不会再请求
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fetchUtxosByTxid(ctx, utxos, 2, func(ctx context.Context, txHash chainhash.Hash) ([]rawTxVout, error) {
+		t.Fatal("should not fetch after cancel")
+		return nil, nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewCustomParamFromMsgTxWithContext(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param, signParam := caseNewVerifyReportSignParam(t, senderAddress, privateKeyHex, &netParams)
+
+	var utxoMap = make(map[wire.OutPoint]*SenderAmountUtxo, len(param.VinList))
+	for _, vin := range param.VinList {
+		utxoMap[vin.OutPoint] = NewSenderAmountUtxo(NewAddressTuple(senderAddress), vin.Amount)
+	}
+	preImp := NewSenderAmountUtxoCache(utxoMap)
+
+	customParam, err := NewCustomParamFromMsgTxWithContext(context.Background(), signParam.MsgTx, preImp)
+	require.NoError(t, err)
+	expected, err := NewCustomParamFromMsgTx(signParam.MsgTx, preImp)
+	require.NoError(t, err)
+	require.Equal(t, expected, customParam)
+	require.NoError(t, customParam.VerifyMsgTxSign(signParam.MsgTx, &netParams))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewCustomParamFromMsgTxWithContext(ctx, signParam.MsgTx, preImp)
+	require.ErrorIs(t, err, context.Canceled)
+}
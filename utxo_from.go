@@ -1,7 +1,15 @@
 package gobtcsign
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/pkg/errors"
@@ -12,32 +20,136 @@ type GetUtxoFromInterface interface {
 }
 
 type SenderAmountUtxoClient struct {
-	client *rpcclient.Client
+	client      *rpcclient.Client
+	Parallelism int //批量获取时的并发数，为0时使用 DefaultUtxoFetchParallelism，详见 GetUtxosFrom
 }
 
 func NewSenderAmountUtxoClient(client *rpcclient.Client) *SenderAmountUtxoClient {
 	return &SenderAmountUtxoClient{client: client}
 }
 
+// GetUtxoFrom 通过节点的 getrawtransaction 获取前置输出
+// 使用输出的脚本而不是节点返回的地址，因为 P2PK、裸多签和非标准脚本的输出是没有地址的，需要展示地址时使用 ResolveAddresses 反推
+// 只解析请求的那个输出，同一个交易里其它输出的数据不对时不影响这里
 func (uc *SenderAmountUtxoClient) GetUtxoFrom(utxo wire.OutPoint) (*SenderAmountUtxo, error) {
-	previousUtxoTx, err := GetRawTransaction(uc.client, utxo.Hash.String())
+	vouts, err := getRawTransactionVouts(uc.client, utxo.Hash)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get-raw-transaction")
 	}
-	previousOutput := previousUtxoTx.Vout[utxo.Index]
+	return pickSenderAmountUtxo(vouts, utxo)
+}
 
-	previousAmount, err := btcutil.NewAmount(previousOutput.Value)
+// rawTxVout getrawtransaction 返回结果里每个输出需要的部分，数量使用 json.Number 以免按浮点数解析
+// btcjson.TxRawResult 的数量是 float64，数量很大时转换成聪可能有舍入误差
+type rawTxVout struct {
+	Value        json.Number `json:"value"`
+	N            uint32      `json:"n"`
+	ScriptPubKey struct {
+		Hex string `json:"hex"`
+	} `json:"scriptPubKey"`
+}
+
+// toSenderAmountUtxo 把节点返回的输出转换为前置输出，按十进制文本得到精确的聪数，脚本使用节点返回的 hex
+func (vout *rawTxVout) toSenderAmountUtxo() (*SenderAmountUtxo, error) {
+	amount, err := parseDecimalAmount(vout.Value.String())
 	if err != nil {
 		return nil, errors.WithMessage(err, "get-previous-amount")
 	}
+	pkScript, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong decode script-pub-key hex")
+	}
+	return NewSenderAmountUtxo(&AddressTuple{PkScript: pkScript}, int64(amount)), nil
+}
 
-	utxoFrom := NewSenderAmountUtxo(
-		NewAddressTuple(previousOutput.ScriptPubKey.Address),
-		int64(previousAmount),
-	)
+// GetRawTransactionUtxos 通过 getrawtransaction 得到交易的全部输出，位置和交易的输出相同
+// 任何一个输出解析失败时都返回错误，只需要其中某个输出时使用 SenderAmountUtxoClient.GetUtxoFrom
+func GetRawTransactionUtxos(client *rpcclient.Client, txHash chainhash.Hash) ([]*SenderAmountUtxo, error) {
+	vouts, err := getRawTransactionVouts(client, txHash)
+	if err != nil {
+		return nil, err
+	}
+	var results = make([]*SenderAmountUtxo, 0, len(vouts))
+	for idx := range vouts {
+		utxoFrom, err := vouts[idx].toSenderAmountUtxo()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "wrong vout n=%d", idx)
+		}
+		results = append(results, utxoFrom)
+	}
+	return results, nil
+}
+
+// getRawTransactionVouts 通过 getrawtransaction 得到交易的全部输出，这里直接解析节点返回的 JSON，但是不转换数量和脚本
+func getRawTransactionVouts(client *rpcclient.Client, txHash chainhash.Hash) ([]rawTxVout, error) {
+	params := []json.RawMessage{
+		json.RawMessage(strconv.Quote(txHash.String())),
+		json.RawMessage("1"), //verbose，和 rpcclient 的 GetRawTransactionVerbose 相同，比特币和狗狗币的节点都支持
+	}
+	data, err := client.RawRequest("getrawtransaction", params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong raw-request")
+	}
+	var rawTx struct {
+		Vout []rawTxVout `json:"vout"`
+	}
+	if err := json.Unmarshal(data, &rawTx); err != nil {
+		return nil, errors.WithMessage(err, "wrong unmarshal raw-tx")
+	}
+	for idx, vout := range rawTx.Vout {
+		if int(vout.N) != idx {
+			return nil, errors.Errorf("wrong vout n=%d at position=%d", vout.N, idx)
+		}
+	}
+	return rawTx.Vout, nil
+}
+
+// pickSenderAmountUtxo 按 outpoint 的位置取出前置输出并解析，位置超出范围时返回 *OutputIndexError
+func pickSenderAmountUtxo(vouts []rawTxVout, utxo wire.OutPoint) (*SenderAmountUtxo, error) {
+	if int(utxo.Index) >= len(vouts) {
+		return nil, errors.WithStack(&OutputIndexError{TxHash: utxo.Hash.String(), Index: utxo.Index, Count: len(vouts)})
+	}
+	utxoFrom, err := vouts[utxo.Index].toSenderAmountUtxo()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "wrong utxo[%s:%d]", utxo.Hash.String(), utxo.Index)
+	}
 	return utxoFrom, nil
 }
 
+// maxDecimalAmountExponent 数量的科学计数法的指数上限，避免很大的指数让 big.Rat 占用过多的内存
+const maxDecimalAmountExponent = 32
+
+// decimalAmountRegexp 非负的十进制数量，可以带小数和科学计数法的指数
+var decimalAmountRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// parseDecimalAmount 把节点返回的十进制数量（单位是币）精确地转换为聪，不经过浮点数
+// Bitcoin Core 返回定长的8位小数，而 btcd 按 float64 输出，很小的数量会使用科学计数法，比如 1e-07，这里都按十进制精确转换
+// 数量不能是负数，不能超过 btcutil.MaxSatoshi，也不能有超过聪的精度，不符合时返回错误
+func parseDecimalAmount(value string) (btcutil.Amount, error) {
+	//先按 JSON 数字的格式检查，big.Rat 还支持分数和十六进制等写法，这里都不需要
+	if !decimalAmountRegexp.MatchString(value) {
+		return 0, errors.Errorf("wrong decimal amount=%s", value)
+	}
+	if idx := strings.IndexAny(value, "eE"); idx >= 0 {
+		exp, err := strconv.Atoi(value[idx+1:])
+		if err != nil || exp > maxDecimalAmountExponent || exp < -maxDecimalAmountExponent {
+			return 0, errors.Errorf("wrong decimal amount=%s exponent", value)
+		}
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return 0, errors.Errorf("wrong decimal amount=%s", value)
+	}
+	rat.Mul(rat, new(big.Rat).SetInt64(btcutil.SatoshiPerBitcoin))
+	if !rat.IsInt() {
+		return 0, errors.Errorf("wrong decimal amount=%s not exact satoshi", value)
+	}
+	if rat.Sign() < 0 || rat.Num().Cmp(big.NewInt(btcutil.MaxSatoshi)) > 0 {
+		return 0, errors.Errorf("wrong decimal amount=%s out of range [0, max-satoshi=%d]", value, int64(btcutil.MaxSatoshi))
+	}
+	return btcutil.Amount(rat.Num().Int64()), nil
+}
+
 type SenderAmountUtxo struct {
 	sender *AddressTuple
 	amount int64
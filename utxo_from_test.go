@@ -1,7 +1,20 @@
 package gobtcsign
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUtxoFromClient_GetUtxoFrom(t *testing.T) {
@@ -11,3 +24,149 @@ func TestUtxoFromClient_GetUtxoFrom(t *testing.T) {
 func TestSenderAmountUtxoCache_GetUtxoFrom(t *testing.T) {
 	var _ GetUtxoFromInterface = &SenderAmountUtxoCache{}
 }
+
+// caseNewRawTxRpcServer 本地的 JSON-RPC 节点替身，只支持 getrawtransaction，返回的数量是写死的十进制文本
+// 第一个输出是 P2WPKH，第二个是 P2PK，第三个是非标准脚本，后两个节点不会返回地址
+// 第四个是 btcd 按 float64 输出的科学计数法数量，第五个的数量超出聪的精度，只有请求它时才报错
+// 请求不对时返回 HTTP 错误，由测试的协程里的 rpcclient 收到错误，不在服务端的协程里断言
+func caseNewRawTxRpcServer(t *testing.T, callCount *int32) *SenderAmountUtxoClient {
+	const rawTxResult = `{
+		"txid": "%s",
+		"vout": [
+			{"value": 20999999.97690000, "n": 0, "scriptPubKey": {"hex": "001462152b40d8b2cbac358541d850c079ea10d1407f", "address": "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap", "type": "witness_v0_keyhash"}},
+			{"value": 0.00000546, "n": 1, "scriptPubKey": {"hex": "210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac", "type": "pubkey"}},
+			{"value": 0.1, "n": 2, "scriptPubKey": {"hex": "51", "type": "nonstandard"}},
+			{"value": 1e-07, "n": 3, "scriptPubKey": {"hex": "51", "type": "nonstandard"}},
+			{"value": 0.000000001, "n": 4, "scriptPubKey": {"hex": "51", "type": "nonstandard"}}
+		]
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+			ID     json.RawMessage   `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Method != "getrawtransaction" || len(request.Params) == 0 {
+			http.Error(w, "wrong method="+request.Method, http.StatusBadRequest)
+			return
+		}
+		atomic.AddInt32(callCount, 1)
+
+		var txid string
+		if err := json.Unmarshal(request.Params[0], &txid); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := strings.Replace(rawTxResult, "%s", txid, 1)
+		_, _ = w.Write([]byte(`{"result":` + result + `,"error":null,"id":` + string(request.ID) + `}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(server.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(client.Shutdown)
+	return NewSenderAmountUtxoClient(client)
+}
+
+func TestSenderAmountUtxoClient_GetUtxoFrom_RpcServer(t *testing.T) {
+	netParams := chaincfg.TestNet3Params
+
+	var callCount int32
+	uc := caseNewRawTxRpcServer(t, &callCount)
+	txHash := chainhash.HashH([]byte("prev-tx"))
+
+	//数量按十进制文本精确转换，不经过浮点数
+	utxoFrom, err := uc.GetUtxoFrom(*wire.NewOutPoint(&txHash, 0))
+	require.NoError(t, err)
+	require.Equal(t, int64(2099999997690000), utxoFrom.amount)
+	require.Empty(t, utxoFrom.sender.Address)
+	address, err := utxoFrom.sender.GetAddress(&netParams)
+	require.NoError(t, err)
+	require.Equal(t, "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap", address)
+
+	//P2PK 和非标准脚本没有地址，但是有脚本，仍然可以用来拼交易和验签
+	utxoFrom, err = uc.GetUtxoFrom(*wire.NewOutPoint(&txHash, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(546), utxoFrom.amount)
+	require.Len(t, utxoFrom.sender.PkScript, 35)
+
+	utxoFrom, err = uc.GetUtxoFrom(*wire.NewOutPoint(&txHash, 2))
+	require.NoError(t, err)
+	require.Equal(t, int64(10000000), utxoFrom.amount)
+	require.Equal(t, []byte{0x51}, utxoFrom.sender.PkScript)
+
+	//btcd 的科学计数法
+	utxoFrom, err = uc.GetUtxoFrom(*wire.NewOutPoint(&txHash, 3))
+	require.NoError(t, err)
+	require.Equal(t, int64(10), utxoFrom.amount)
+
+	//只有请求的输出数量不对时才报错
+	_, err = uc.GetUtxoFrom(*wire.NewOutPoint(&txHash, 4))
+	require.Error(t, err)
+	t.Log(err)
+
+	//输出的位置超出范围
+	_, err = uc.GetUtxoFrom(*wire.NewOutPoint(&txHash, 5))
+	require.ErrorIs(t, err, ErrOutputIndex)
+	var indexErr *OutputIndexError
+	require.ErrorAs(t, err, &indexErr)
+	require.Equal(t, 5, indexErr.Count)
+	require.Equal(t, int32(6), atomic.LoadInt32(&callCount))
+
+	//需要全部输出时，任何一个输出不对都报错
+	_, err = GetRawTransactionUtxos(uc.client, txHash)
+	require.Error(t, err)
+}
+
+func TestSenderAmountUtxoClient_GetUtxosFrom_RpcServer(t *testing.T) {
+	var callCount int32
+	uc := caseNewRawTxRpcServer(t, &callCount)
+	uc.Parallelism = 2
+
+	var utxos []wire.OutPoint
+	for idx := 0; idx < 5; idx++ {
+		txHash := chainhash.HashH([]byte{byte(idx)})
+		utxos = append(utxos, *wire.NewOutPoint(&txHash, 0), *wire.NewOutPoint(&txHash, 2), *wire.NewOutPoint(&txHash, 3))
+	}
+	utxoMap, err := uc.GetUtxosFrom(context.Background(), utxos)
+	require.NoError(t, err)
+	require.Len(t, utxoMap, 15)
+	require.Equal(t, int32(5), atomic.LoadInt32(&callCount)) //每个交易只请求一次
+	require.Equal(t, int64(10000000), utxoMap[utxos[1]].amount)
+	require.Equal(t, int64(10), utxoMap[utxos[2]].amount)
+}
+
+func TestParseDecimalAmount(t *testing.T) {
+	for value, expected := range map[string]btcutil.Amount{
+		"0":                 0,
+		"0.00000001":        1,
+		"1":                 100000000,
+		"0.1":               10000000,
+		"20999999.97690000": 2099999997690000,
+		"21000000":          btcutil.MaxSatoshi,
+		"1e-05":             1000,
+		"1e-07":             10,
+		"5.46E-06":          546,
+		"2.1e+07":           2100000000000000,
+	} {
+		amount, err := parseDecimalAmount(value)
+		require.NoError(t, err)
+		require.Equal(t, expected, amount, value)
+	}
+
+	for _, value := range []string{"", ".1", "-1", "1e-09", "0.000000001", "1.2.3", "92233720369", "92233720368.5", "21000000.00000001", "-0.00000001", "abc", "1/2", "0x10", "1e100000000"} {
+		_, err := parseDecimalAmount(value)
+		require.Error(t, err, value)
+	}
+}
@@ -0,0 +1,174 @@
+package gobtcsign
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+)
+
+// VerifyInputResult 单个输入的验签结果
+type VerifyInputResult struct {
+	Index       int                  //输入的位置
+	ScriptClass txscript.ScriptClass //前置输出的脚本类型
+	SigHashType txscript.SigHashType //签名里的 sighash 类型，没有签名时是0
+	Signature   []byte               //从解锁脚本或者见证里取出的签名，包含最后的 sighash 字节
+	PubKey      []byte               //从解锁脚本或者见证里取出的公钥，taproot 和 P2A 没有
+	ErrorCode   txscript.ErrorCode   //验签失败时脚本引擎的错误码，详见 VerifyError
+	Err         error                //验签失败的错误，为空表示验签通过
+}
+
+// IsValid 验签是否通过
+func (res *VerifyInputResult) IsValid() bool {
+	return res.Err == nil
+}
+
+// VerifyReport 整个交易的验签报告，和 VerifySign 不同，它会检查全部的输入，而不是遇到第一个错误就返回
+type VerifyReport struct {
+	Results []*VerifyInputResult //和交易的输入一一对应
+}
+
+// Failures 验签失败的那些输入
+func (report *VerifyReport) Failures() []*VerifyInputResult {
+	var failures []*VerifyInputResult
+	for _, res := range report.Results {
+		if !res.IsValid() {
+			failures = append(failures, res)
+		}
+	}
+	return failures
+}
+
+// Err 汇总的错误，全部输入都通过时返回空，否则返回 *VerifyReportError
+func (report *VerifyReport) Err() error {
+	if failures := report.Failures(); len(failures) > 0 {
+		return &VerifyReportError{Failures: failures}
+	}
+	return nil
+}
+
+// VerifyReportError 汇总了全部验签失败的输入，可以使用 errors.Is 判断 ErrVerifySign，也可以使用 errors.As 得到第一个 *VerifyError
+type VerifyReportError struct {
+	Failures []*VerifyInputResult
+}
+
+func (e *VerifyReportError) Error() string {
+	var messages = make([]string, 0, len(e.Failures))
+	for _, res := range e.Failures {
+		messages = append(messages, res.Err.Error())
+	}
+	return fmt.Sprintf("verify-sign-failed count=%d: [%s]", len(e.Failures), strings.Join(messages, "; "))
+}
+
+func (e *VerifyReportError) Unwrap() []error {
+	var errs = make([]error, 0, len(e.Failures))
+	for _, res := range e.Failures {
+		errs = append(errs, res.Err)
+	}
+	return errs
+}
+
+func (e *VerifyReportError) Is(target error) bool {
+	return target == ErrVerifySign
+}
+
+// VerifySignReport 检查全部输入的签名，得到每个输入的验签结果
+// parallelism 是并发验签的协程数，<=1 时逐个验证，输入很多时（比如归集交易）可以设置为 runtime.NumCPU()
+// 返回的错误只表示参数不对，验签失败的信息在报告里，使用 VerifyReport.Err 得到汇总的错误
+func VerifySignReport(msgTx *wire.MsgTx, inputOuts []*wire.TxOut, parallelism int) (*VerifyReport, error) {
+	//提前检查，以免并发验签时在协程里 panic
+	if err := checkInputOuts(msgTx, inputOuts); err != nil {
+		return nil, err
+	}
+	var prevOutsMap = make(map[wire.OutPoint]*wire.TxOut, len(msgTx.TxIn))
+	for idx, txIn := range msgTx.TxIn {
+		prevOutsMap[txIn.PreviousOutPoint] = inputOuts[idx]
+	}
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(prevOutsMap)
+	sigHashes := txscript.NewTxSigHashes(msgTx, prevOutFetcher)
+	sigCache := txscript.NewSigCache(uint(len(msgTx.TxIn))) //SigCache 是并发安全的，多个协程共用就行
+
+	var results = make([]*VerifyInputResult, len(msgTx.TxIn))
+	verifyInput := func(idx int) {
+		res := newVerifyInputResult(msgTx.TxIn[idx], idx, inputOuts[idx])
+		if err := verifyInputSignWithCache(msgTx, idx, inputOuts[idx], prevOutFetcher, sigHashes, sigCache); err != nil {
+			res.Err = err
+			var verifyErr *VerifyError
+			if errors.As(err, &verifyErr) {
+				res.ErrorCode = verifyErr.ErrorCode
+			}
+		}
+		results[idx] = res //每个协程只写自己位置的结果，因此不需要加锁
+	}
+
+	if parallelism <= 1 {
+		for idx := range msgTx.TxIn {
+			verifyInput(idx)
+		}
+		return &VerifyReport{Results: results}, nil
+	}
+
+	var wg sync.WaitGroup
+	var sem = make(chan struct{}, parallelism)
+	for idx := range msgTx.TxIn {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			verifyInput(idx)
+		}(idx)
+	}
+	wg.Wait()
+	return &VerifyReport{Results: results}, nil
+}
+
+// VerifyMsgTxSignReport 和 VerifyMsgTxSign 相同，但是检查全部的输入，得到验签报告
+func (param *BitcoinTxParams) VerifyMsgTxSignReport(msgTx *wire.MsgTx, netParams *chaincfg.Params, parallelism int) (*VerifyReport, error) {
+	//交易里的输入可能被重新排列过，因此按 OutPoint 找到每个输入对应的参数
+	vinList, err := param.alignVinList(msgTx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong align-vin-list")
+	}
+	inputsItem, err := (&BitcoinTxParams{VinList: vinList}).GetVerifyTxInputsItem(netParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "wrong get-inputs")
+	}
+	return VerifySignReport(msgTx, NewInputOutsV2(inputsItem.PkScripts, inputsItem.InAmounts), parallelism)
+}
+
+// newVerifyInputResult 从解锁脚本或者见证里取出签名和公钥，这里只识别常见的格式，取不出来时就是空的
+func newVerifyInputResult(txIn *wire.TxIn, idx int, inputOut *wire.TxOut) *VerifyInputResult {
+	res := &VerifyInputResult{
+		Index:       idx,
+		ScriptClass: txscript.GetScriptClass(inputOut.PkScript),
+	}
+	var items [][]byte
+	if len(txIn.Witness) > 0 {
+		items = txIn.Witness
+	} else if pushes, err := txscript.PushedData(txIn.SignatureScript); err == nil {
+		items = pushes
+	}
+	if len(items) == 0 || IsPayToAnchor(inputOut.PkScript) {
+		return res
+	}
+
+	res.Signature = items[0]
+	switch {
+	case res.ScriptClass == txscript.WitnessV1TaprootTy && len(res.Signature) == 64:
+		res.SigHashType = txscript.SigHashDefault //taproot 的签名是64字节时表示默认的 sighash
+	case len(res.Signature) > 0:
+		res.SigHashType = txscript.SigHashType(res.Signature[len(res.Signature)-1])
+	}
+	if len(items) > 1 {
+		if pubKey := items[1]; len(pubKey) == btcec.PubKeyBytesLenCompressed || len(pubKey) == 65 { //33字节是压缩的公钥，65字节是不压缩的公钥
+			res.PubKey = pubKey
+		}
+	}
+	return res
+}
@@ -0,0 +1,130 @@
+package gobtcsign
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func caseNewVerifyReportSignParam(t *testing.T, senderAddress string, privateKeyHex string, netParams *chaincfg.Params) (*BitcoinTxParams, *SignParam) {
+	var param = &BitcoinTxParams{RBFInfo: *NewRBFActive()}
+	var amount int64
+	for idx := 0; idx < 6; idx++ {
+		param.VinList = append(param.VinList, VinType{
+			OutPoint: *MustNewOutPoint("e1f05d4ef10d6d4245839364c637cc37f429784883761668978645c67e723919", uint32(idx)),
+			Sender:   *NewAddressTuple(senderAddress),
+			Amount:   int64(10000 + idx),
+		})
+		amount += int64(10000 + idx)
+	}
+	param.OutList = []OutType{{Target: *NewAddressTuple(senderAddress), Amount: amount - 2000}}
+
+	signParam, err := param.CreateTxSignParams(netParams)
+	require.NoError(t, err)
+	require.NoError(t, Sign(senderAddress, privateKeyHex, signParam))
+	return param, signParam
+}
+
+func TestVerifySignReport(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	param, signParam := caseNewVerifyReportSignParam(t, senderAddress, privateKeyHex, &netParams)
+
+	report, err := param.VerifyMsgTxSignReport(signParam.MsgTx, &netParams, 0)
+	require.NoError(t, err)
+	require.NoError(t, report.Err())
+	require.Len(t, report.Results, 6)
+	for idx, res := range report.Results {
+		require.True(t, res.IsValid())
+		require.Equal(t, idx, res.Index)
+		require.Equal(t, txscript.WitnessV0PubKeyHashTy, res.ScriptClass)
+		require.Equal(t, txscript.SigHashAll, res.SigHashType)
+		require.Len(t, res.PubKey, btcec.PubKeyBytesLenCompressed)
+		require.NotEmpty(t, res.Signature)
+	}
+
+	//隔离见证的签名包含了输入的数量，把其中两个输入的数量改错
+	param.VinList[1].Amount++
+	param.VinList[4].Amount++
+	for _, parallelism := range []int{0, 1, 4} {
+		report, err := param.VerifyMsgTxSignReport(signParam.MsgTx, &netParams, parallelism)
+		require.NoError(t, err)
+		require.Len(t, report.Results, 6)
+
+		failures := report.Failures()
+		require.Len(t, failures, 2)
+		require.Equal(t, 1, failures[0].Index)
+		require.Equal(t, 4, failures[1].Index)
+		require.Equal(t, txscript.ErrNullFail, failures[0].ErrorCode)
+
+		err = report.Err()
+		require.ErrorIs(t, err, ErrVerifySign)
+		var verifyErr *VerifyError
+		require.True(t, errors.As(err, &verifyErr))
+		require.Equal(t, 1, verifyErr.Index)
+		require.Contains(t, err.Error(), "count=2")
+		t.Log(err)
+	}
+}
+
+func TestVerifySignReport_MissingInputOut(t *testing.T) {
+	const senderAddress = "tb1qvg2jksxckt96cdv9g8v9psreaggdzsrlm6arap"
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	_, signParam := caseNewVerifyReportSignParam(t, senderAddress, privateKeyHex, &netParams)
+
+	//前置输出是空的时返回错误，并发验签时也不会在协程里 panic
+	inputOuts := append([]*wire.TxOut{}, signParam.InputOuts...)
+	inputOuts[3] = nil
+	for _, parallelism := range []int{0, 4} {
+		_, err := VerifySignReport(signParam.MsgTx, inputOuts, parallelism)
+		require.ErrorIs(t, err, ErrInputOutMissing)
+		t.Log(err)
+	}
+	require.ErrorIs(t, VerifySign(signParam.MsgTx, inputOuts, nil, nil), ErrInputOutMissing)
+}
+
+func TestVerifySignReport_P2PKH(t *testing.T) {
+	const privateKeyHex = "54bb1426611226077889d63c65f4f1fa212bcb42c2141c81e0c5409324711092" //注意不要暴露私钥，除非准备放弃这个钱包
+
+	netParams := chaincfg.TestNet3Params
+
+	privKeyBytes, err := hex.DecodeString(privateKeyHex)
+	require.NoError(t, err)
+	_, pubKey := btcec.PrivKeyFromBytes(privKeyBytes)
+	address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), &netParams)
+	require.NoError(t, err)
+
+	_, signParam := caseNewVerifyReportSignParam(t, address.EncodeAddress(), privateKeyHex, &netParams)
+
+	report, err := VerifySignReport(signParam.MsgTx, signParam.InputOuts, 2)
+	require.NoError(t, err)
+	require.NoError(t, report.Err())
+	for _, res := range report.Results {
+		require.Equal(t, txscript.PubKeyHashTy, res.ScriptClass)
+		require.Equal(t, txscript.SigHashAll, res.SigHashType)
+		require.Equal(t, pubKey.SerializeCompressed(), res.PubKey)
+	}
+
+	//签名被篡改
+	signParam.MsgTx.TxIn[2].SignatureScript = signParam.MsgTx.TxIn[3].SignatureScript
+	report, err = VerifySignReport(signParam.MsgTx, signParam.InputOuts, 2)
+	require.NoError(t, err)
+	require.Len(t, report.Failures(), 1)
+	require.Equal(t, 2, report.Failures()[0].Index)
+
+	_, err = VerifySignReport(signParam.MsgTx, signParam.InputOuts[:1], 0)
+	require.Error(t, err)
+}
@@ -112,6 +112,9 @@ func VerifySignV3(msgTx *wire.MsgTx, inputsItem *VerifyTxInputsType) error {
 // 这个 github 官方包 是非常重要的参考资料
 // https://github.com/btcsuite/btcwallet/blob/master/wallet/createtx.go
 func VerifySignV4(msgTx *wire.MsgTx, prevScripts [][]byte, inputValues []btcutil.Amount) error {
+	if count := min(len(prevScripts), len(inputValues)); count < len(msgTx.TxIn) {
+		return errors.WithStack(&InputIndexError{Index: len(msgTx.TxIn) - 1, Count: count})
+	}
 	inputFetcher, err := txauthor.TXPrevOutFetcher(msgTx, prevScripts, inputValues)
 	if err != nil {
 		return errors.WithMessage(err, "wrong cannot-create-pre-out-cache")